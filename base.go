@@ -1,30 +1,179 @@
 package candyjs
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
 	"reflect"
+	"sort"
 	"unsafe"
 
 	"github.com/olebedev/go-duktape"
 )
 
-const goProxyPtrProp = "\xff" + "goProxyPtrProp"
+const (
+	goProxyPtrProp       = "\xff" + "goProxyPtrProp"
+	goFunctionRefProp    = "\xff" + "goFunctionRefProp"
+	goPrevFinalizerProp  = "\xff" + "goPrevFinalizerProp"
+	storageFinalizerProp = "\xff" + "storageFinalizerProp"
+	storageStateProp     = "\xff" + "storageStateProp"
+)
+
+// flags of duk_def_prop, not exposed by go-duktape, as defined at duktape.h
+const (
+	defPropWritable         = 1 << 0
+	defPropEnumerable       = 1 << 1
+	defPropConfigurable     = 1 << 2
+	defPropHaveWritable     = 1 << 3
+	defPropHaveEnumerable   = 1 << 4
+	defPropHaveConfigurable = 1 << 5
+	defPropHaveValue        = 1 << 6
+	defPropHaveGetter       = 1 << 7
+	defPropHaveSetter       = 1 << 8
+)
 
 // Context represents a Duktape thread and its call and value stacks.
 type Context struct {
-	storage *storage
+	storage     *storage
+	fs          fs.FS
+	esModules   bool
+	transformer Transformer
+	sourceMaps  map[string][]*SourceMap
+	byteStrings bool
+	// packages are the packages only available on this Context, like the
+	// built-in modules, having precedence over the registered ones
+	packages map[string]PackagePusher
+	// externalBytes keeps referenced the bytes of the external buffers, until
+	// the heap is destroyed
+	externalBytes [][]byte
+	// interrupted is set when the Context is interrupted, see interrupt
+	interrupted int32
+	// done is cancelled when the Context is interrupted, stopping the requests
+	// of fetch
+	done   context.Context
+	cancel context.CancelFunc
+	// options are the Options given at creation, used to create alike Contexts
+	options Options
 	*duktape.Context
 }
 
+// Options configures a Context created with NewContextWithOptions.
+type Options struct {
+	// FS is the file system where PevalFile, EvalFile and CandyJS.include
+	// read the scripts from, the module loader is installed using it as root,
+	// see SetModuleFS. If nil PevalFile and EvalFile read from the OS file
+	// system and CandyJS.include is not defined.
+	FS fs.FS
+	// ESModules enables the import and export statements on the scripts and
+	// modules read from files, they are transformed to the CommonJS style of
+	// the module loader. The errors are reported at the original lines. The
+	// export statements are only allowed on modules, the scripts exporting
+	// any name fail with a SyntaxError.
+	ESModules bool
+	// Transformer is applied to the source of every script before is
+	// compiled, including the evaluated strings, the files and the modules.
+	Transformer Transformer
+	// Console receives the messages of the `console` global, by default are
+	// written to Stdout and Stderr.
+	Console Console
+	// Stdout and Stderr are the writers used by the `print` and `alert`
+	// globals respectively, by default the standard output and error.
+	Stdout, Stderr io.Writer
+	// Fetch is the RoundTripper used by the `fetch` global, that is only
+	// defined if given. It can restrict the destinations or add credentials
+	// to the requests, http.DefaultTransport allows any request.
+	Fetch http.RoundTripper
+	// FileSystem enables the `fs` module, giving access to the files of a
+	// directory or a fs.FS, it is available with `CandyJS.require('fs')`, or
+	// `require('fs')` when the module loader is installed.
+	FileSystem *FileSystem
+	// ByteStrings pushes the []byte values as strings, instead of buffers, as
+	// the previous versions did.
+	ByteStrings bool
+}
+
 // NewContext returns a new Context
 func NewContext() *Context {
-	ctx := &Context{Context: duktape.New()}
+	return NewContextWithOptions(Options{})
+}
+
+// NewContextWithOptions returns a new Context configured with the given
+// Options.
+func NewContextWithOptions(opts Options) *Context {
+	ctx := &Context{
+		Context:     duktape.New(),
+		fs:          opts.FS,
+		esModules:   opts.ESModules,
+		byteStrings: opts.ByteStrings,
+		options:     opts,
+	}
+
+	ctx.done, ctx.cancel = context.WithCancel(context.Background())
 	ctx.storage = newStorage()
+	ctx.pushStorageFinalizer()
 	ctx.pushGlobalCandyJSObject()
+	ctx.transformer = opts.Transformer
+
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+
+	if opts.Console == nil {
+		opts.Console = NewWriterConsole(opts.Stdout, opts.Stderr)
+	}
+
+	ctx.pushGlobalPrint("print", opts.Stdout)
+	ctx.pushGlobalPrint("alert", opts.Stderr)
+	ctx.pushGlobalConsole(opts.Console)
+	ctx.pushGlobalEncoding()
+
+	if opts.Fetch != nil {
+		ctx.pushGlobalFetch(opts.Fetch)
+	}
+
+	if opts.FileSystem != nil {
+		ctx.packages = map[string]PackagePusher{"fs": opts.FileSystem.pushModule}
+	}
+
+	if opts.FS != nil {
+		ctx.pushInclude()
+		ctx.SetModuleFS(opts.FS)
+	}
 
 	return ctx
 }
 
+// pushInclude defines `CandyJS.include`, evaluating a file of the FS given at
+// the Options on the global scope.
+func (ctx *Context) pushInclude() {
+	ctx.PushGlobalObject()
+	ctx.GetPropString(-1, "CandyJS")
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		ctx.pcompileFile(ctx.GetString(0))
+		return 1
+	})
+
+	ctx.PutPropString(-2, "_compileFile")
+	ctx.Pop2()
+
+	ctx.Context.EvalString(`CandyJS.include = function(filename) {
+		var fn = CandyJS._compileFile(filename);
+		if (fn instanceof Error) {
+			throw fn;
+		}
+
+		return fn();
+	}`)
+}
+
 func (ctx *Context) pushGlobalCandyJSObject() {
 	ctx.PushGlobalObject()
 	ctx.PushObject()
@@ -37,6 +186,8 @@ func (ctx *Context) pushGlobalCandyJSObject() {
 	ctx.PutPropString(-2, "CandyJS")
 	ctx.Pop()
 
+	ctx.EvalString(bufferDataJS)
+
 	ctx.EvalString(`CandyJS._call = function(ptr, args) {
 		return CandyJS._functions[ptr].apply(null, args)
 	}`)
@@ -61,6 +212,76 @@ func (ctx *Context) SetRequireFunction(f interface{}) int {
 	return idx
 }
 
+// PutPropStringReadOnly like PutPropString but the property is defined as
+// non-writable and non-configurable, so it cannot be replaced or deleted from
+// JavaScript. The value at the top of the stack is popped.
+func (ctx *Context) PutPropStringReadOnly(objIndex int, key string) {
+	objIndex = ctx.NormalizeIndex(objIndex)
+	ctx.PushString(key)
+	ctx.Swap(-2, -1)
+	ctx.DefProp(objIndex, defPropHaveValue|
+		defPropHaveWritable|
+		defPropHaveConfigurable|
+		defPropHaveEnumerable|defPropEnumerable,
+	)
+}
+
+// PutPropStringLazy defines a property whose value is pushed by the given
+// function the first time the property is read, replacing the property by the
+// value, so its cost is only paid if used. Unlike PutPropString no value is
+// popped from the stack.
+func (ctx *Context) PutPropStringLazy(objIndex int, key string, push func()) {
+	ctx.putPropStringLazy(objIndex, key, push, false)
+}
+
+// PutPropStringLazyReadOnly like PutPropStringLazy but the value is defined
+// as PutPropStringReadOnly does.
+func (ctx *Context) PutPropStringLazyReadOnly(objIndex int, key string, push func()) {
+	ctx.putPropStringLazy(objIndex, key, push, true)
+}
+
+func (ctx *Context) putPropStringLazy(objIndex int, key string, push func(), readOnly bool) {
+	define := func() {
+		flags := defPropHaveValue | defPropHaveWritable | defPropHaveConfigurable |
+			defPropHaveEnumerable | defPropEnumerable
+		if !readOnly {
+			flags |= defPropWritable | defPropConfigurable
+		}
+
+		ctx.PushThis()
+		ctx.PushString(key)
+		ctx.Dup(-3)
+		ctx.DefProp(-3, uint(flags))
+		ctx.Pop()
+	}
+
+	objIndex = ctx.NormalizeIndex(objIndex)
+	ctx.PushString(key)
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		push()
+		define()
+		return 1
+	})
+
+	ctx.PushBoolean(true)
+	ctx.PutPropString(-2, lazyGetterProp)
+
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		// the key is given as second argument by duktape
+		ctx.SetTop(1)
+		if !readOnly {
+			define()
+		}
+
+		return 0
+	})
+
+	ctx.DefProp(objIndex, defPropHaveGetter|defPropHaveSetter|
+		defPropHaveConfigurable|defPropConfigurable|
+		defPropHaveEnumerable|defPropEnumerable,
+	)
+}
+
 // PushGlobalType like PushType but pushed to the global object
 func (ctx *Context) PushGlobalType(name string, s interface{}) int {
 	ctx.PushGlobalObject()
@@ -74,13 +295,161 @@ func (ctx *Context) PushGlobalType(name string, s interface{}) int {
 // PushType push a constructor for the type of the given value, this constructor
 // returns an empty instance of the type. The value passed is discarded, only
 // is used for retrieve the time, instead of require pass a `reflect.Type`.
+//
+// The constructor accepts an optional initial value, converted to the type
+// following the same rules as the arguments of PushGoFunction, so named types
+// can be created from its underlying value, `new Month(3)`. The structs are
+// created from a plain object with the values of its fields, using the same
+// names as the proxy, `new MyModel({int: 42, nested: other})`.
+//
+// The exported methods of the type are defined on the prototype of the
+// constructor, and the instances created by it inherit from it.
 func (ctx *Context) PushType(s interface{}) int {
-	return ctx.PushGoFunction(func() {
-		value := reflect.New(reflect.TypeOf(s))
-		ctx.PushProxy(value.Interface())
+	t := reflect.TypeOf(s)
+
+	cons := ctx.pushNativeFunction(func(*duktape.Context) int {
+		value := reflect.New(t)
+		if ctx.GetTop() > 0 && !ctx.IsNullOrUndefined(0) {
+			if err := ctx.setTypeValue(0, value.Elem()); err != nil {
+				return duktape.ErrRetError
+			}
+		}
+
+		ctx.PushCurrentFunction()
+		ctx.GetPropString(-1, "prototype")
+		ctx.pushProxy(value.Interface(), ctx.NormalizeIndex(-1))
+		return 1
+	})
+
+	ctx.putGoFunctionRef(cons, typeConstructor{t})
+	ctx.pushTypePrototype(cons, reflect.PtrTo(t))
+	ctx.PutPropString(cons, "prototype")
+
+	return cons
+}
+
+// pushTypePrototype push the prototype of a type constructor, with a function
+// per exported method calling the method of `this`.
+func (ctx *Context) pushTypePrototype(cons int, t reflect.Type) {
+	proto := ctx.PushObject()
+	ctx.Dup(cons)
+	ctx.PutPropString(proto, "constructor")
+
+	names := make([]string, 0)
+	for name := range types.get(t).methodsByName {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		ctx.pushMethod(t, name)
+		ctx.PutPropString(proto, nameToJavaScript(name))
+	}
+}
+
+func (ctx *Context) pushMethod(t reflect.Type, name string) {
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		ctx.PushThis()
+		this := ctx.getProxy(-1)
+		ctx.Pop()
+
+		if this == nil {
+			return duktape.ErrRetType
+		}
+
+		// the values, instead of pointers, only have the value receiver methods
+		thisType := reflect.TypeOf(this)
+		if thisType != t && thisType != t.Elem() {
+			return duktape.ErrRetType
+		}
+
+		m := reflect.ValueOf(this).MethodByName(name)
+		if !m.IsValid() {
+			return duktape.ErrRetType
+		}
+
+		info := types.get(m.Type()).fn
+		return ctx.callFunction(m, info, ctx.getFunctionArgs(info))
+	})
+}
+
+// setTypeValue sets the value at the given index to v, the plain objects set
+// the fields of the structs one by one.
+func (ctx *Context) setTypeValue(index int, v reflect.Value) error {
+	if v.Kind() != reflect.Struct || !ctx.IsObject(index) ||
+		ctx.IsArray(index) || ctx.IsFunction(index) || ctx.getProxy(index) != nil {
+		value := ctx.getTypeValue(index, v.Type())
+		if !value.Type().AssignableTo(v.Type()) {
+			return fmt.Errorf("cannot use %s as %s", value.Type(), v.Type())
+		}
+
+		v.Set(value)
+		return nil
+	}
+
+	index = ctx.NormalizeIndex(index)
+	info := types.get(v.Type())
+
+	ctx.Enum(index, duktape.EnumOwnPropertiesOnly)
+	defer ctx.Pop()
+
+	for ctx.Next(-1, true) {
+		key := ctx.SafeToString(-2)
+		field, found := info.field(key)
+		if !found {
+			return fmt.Errorf("%w: %s", ErrUndefinedProperty, key)
+		}
+
+		f := v.FieldByIndex(field)
+		if !f.CanSet() {
+			return fmt.Errorf("%w: %s", ErrUndefinedProperty, key)
+		}
+
+		if err := ctx.setTypeValue(-1, f); err != nil {
+			return err
+		}
+
+		ctx.Pop2()
+	}
+
+	return nil
+}
+
+// PushInterfaceType push a function that asserts that the value given to it
+// implements the interface, returning the same value or throwing an error if
+// not, similar to a Go conversion like `io.Reader(v)`. The given value should be
+// a nil pointer to the interface, like `(*io.Reader)(nil)`.
+func (ctx *Context) PushInterfaceType(ptr interface{}) int {
+	t := reflect.TypeOf(ptr).Elem()
+
+	return ctx.PushGoFunction(func(v interface{}) (interface{}, error) {
+		if v == nil || !reflect.TypeOf(v).Implements(t) {
+			return nil, fmt.Errorf("%T does not implement %s", v, t)
+		}
+
+		return v, nil
 	})
 }
 
+// typeConstructor is stored as reference of the constructors pushed by
+// PushType, since they are not a Go function by themselves.
+type typeConstructor struct {
+	t reflect.Type
+}
+
+func (ctx *Context) getTypeValue(index int, t reflect.Type) reflect.Value {
+	v := ctx.getValueFromContext(index, t)
+	if v.Kind() == reflect.Ptr && v.Type().Elem() == t {
+		v = v.Elem()
+	}
+
+	if !v.Type().AssignableTo(t) && v.Type().ConvertibleTo(t) {
+		v = v.Convert(t)
+	}
+
+	return v
+}
+
 // PushGlobalProxy like PushProxy but pushed to the global object
 func (ctx *Context) PushGlobalProxy(name string, v interface{}) int {
 	ctx.PushGlobalObject()
@@ -96,26 +465,52 @@ func (ctx *Context) PushGlobalProxy(name string, v interface{}) int {
 // the exact same methods and properties from the original value.
 // http://duktape.org/guide.html#virtualization-proxy-object
 func (ctx *Context) PushProxy(v interface{}) int {
-	ptr := ctx.storage.add(v)
+	return ctx.pushProxy(v, -1)
+}
 
+// pushProxy like PushProxy, the proxy inherits from the object at the proto
+// index, if is a valid index.
+func (ctx *Context) pushProxy(v interface{}, proto int) int {
+	return ctx.pushProxyPtr(ctx.addStorage(v), v, proto)
+}
+
+// pushClosingProxy like PushProxy, but the value is closed when the proxy is
+// garbage collected or the heap destroyed, for the values owned by the
+// Context, like the files or the bodies of the responses.
+func (ctx *Context) pushClosingProxy(c io.Closer) int {
+	ctx.releaseStorage()
+	return ctx.pushProxyPtr(ctx.storage.addCloser(c), c, -1)
+}
+
+func (ctx *Context) pushProxyPtr(ptr unsafe.Pointer, v interface{}, proto int) int {
 	obj := ctx.PushObject()
 	ctx.PushPointer(ptr)
 	ctx.PutPropString(-2, goProxyPtrProp)
+	ctx.putStorageFinalizer(obj)
+	if proto >= 0 {
+		ctx.Dup(proto)
+		ctx.SetPrototype(obj)
+	}
 
 	ctx.PushGlobalObject()
 	ctx.GetPropString(-1, "Proxy")
 	ctx.Dup(obj)
 
+	get, has := p.get, p.has
+	if s := newStream(v); s != nil {
+		get, has = s.get, s.has
+	}
+
 	ctx.PushObject()
-	ctx.PushGoFunction(p.enumerate)
+	ctx.pushGoFunction(p.enumerate)
 	ctx.PutPropString(-2, "enumerate")
-	ctx.PushGoFunction(p.enumerate)
+	ctx.pushGoFunction(p.enumerate)
 	ctx.PutPropString(-2, "ownKeys")
-	ctx.PushGoFunction(p.get)
+	ctx.pushGoFunction(get)
 	ctx.PutPropString(-2, "get")
-	ctx.PushGoFunction(p.set)
+	ctx.pushGoFunction(p.set)
 	ctx.PutPropString(-2, "set")
-	ctx.PushGoFunction(p.has)
+	ctx.pushGoFunction(has)
 	ctx.PutPropString(-2, "has")
 	ctx.New(2)
 
@@ -124,6 +519,10 @@ func (ctx *Context) PushProxy(v interface{}) int {
 
 	ctx.PushPointer(ptr)
 	ctx.PutPropString(-2, goProxyPtrProp)
+	if proto >= 0 {
+		ctx.Dup(proto)
+		ctx.SetPrototype(-2)
+	}
 
 	return obj
 }
@@ -206,7 +605,8 @@ func (ctx *Context) PushGlobalInterface(name string, v interface{}) error {
 //  - Bool
 //  - Int, Int8, Int16, Int32, Uint, Uint8, Uint16, Uint32 and Uint64
 //  - Float32 and Float64
-//  - Strings and []byte
+//  - Strings
+//  - []byte, as buffers, or as strings with the ByteStrings option
 //  - Structs
 //  - Functions with any signature
 //
@@ -254,10 +654,15 @@ func (ctx *Context) pushValue(v reflect.Value) error {
 	case reflect.Float64:
 		ctx.PushNumber(v.Float())
 	case reflect.String:
-		ctx.PushString(v.String())
+		ctx.PushString(utf8ToJS(v.String()))
 	case reflect.Struct:
 		ctx.PushProxy(v.Interface())
 	case reflect.Func:
+		if f, ok := v.Interface().(StaticFunction); ok {
+			ctx.PushStaticFunction(f, f)
+			return nil
+		}
+
 		ctx.PushGoFunction(v.Interface())
 	case reflect.Ptr:
 		if v.Elem().Kind() == reflect.Struct {
@@ -267,8 +672,8 @@ func (ctx *Context) pushValue(v reflect.Value) error {
 
 		return ctx.pushValue(v.Elem())
 	case reflect.Slice:
-		if v.Type().Elem().Kind() == reflect.Uint8 {
-			ctx.PushString(string(v.Interface().([]byte)))
+		if isBytes(v.Type()) {
+			ctx.pushBytes(v)
 			return nil
 		}
 
@@ -279,7 +684,7 @@ func (ctx *Context) pushValue(v reflect.Value) error {
 			return err
 		}
 
-		ctx.PushString(string(js))
+		ctx.PushString(utf8ToJS(string(js)))
 		ctx.JsonDecode(-1)
 	}
 
@@ -313,7 +718,17 @@ func (ctx *Context) pushValues(vs []reflect.Value) error {
 
 // PushGlobalGoFunction like PushGoFunction but pushed to the global object
 func (ctx *Context) PushGlobalGoFunction(name string, f interface{}) (int, error) {
-	return ctx.Context.PushGlobalGoFunction(name, ctx.wrapFunction(f))
+	idx, err := ctx.Context.PushGlobalGoFunction(name, ctx.wrapFunction(f))
+	if err != nil {
+		return idx, err
+	}
+
+	ctx.PushGlobalObject()
+	ctx.GetPropString(-1, name)
+	ctx.putGoFunctionRef(-1, f)
+	ctx.Pop2()
+
+	return idx, nil
 }
 
 // PushGoFunction push a native Go function of any signature to the stack.
@@ -354,34 +769,161 @@ func (ctx *Context) PushGlobalGoFunction(name string, f interface{}) (int, error
 // All the non erros returning values are pushed following the same rules of
 // `PushInterface` method
 func (ctx *Context) PushGoFunction(f interface{}) int {
+	idx := ctx.pushGoFunction(f)
+	ctx.putGoFunctionRef(idx, f)
+
+	return idx
+}
+
+func (ctx *Context) pushGoFunction(f interface{}) int {
 	return ctx.Context.PushGoFunction(ctx.wrapFunction(f))
 }
 
+// pushNativeFunction like duktape.Context.PushGoFunction, but the function
+// fails if the Context was interrupted.
+func (ctx *Context) pushNativeFunction(fn func(*duktape.Context) int) int {
+	return ctx.Context.PushGoFunction(func(d *duktape.Context) int {
+		if ctx.isInterrupted() {
+			return duktape.ErrRetError
+		}
+
+		return fn(d)
+	})
+}
+
+// putGoFunctionRef stores a reference to the original Go function on the
+// pushed function, allowing retrieve it later, like Snapshot does.
+func (ctx *Context) putGoFunctionRef(index int, f interface{}) {
+	index = ctx.NormalizeIndex(index)
+	ctx.PushPointer(ctx.addStorage(f))
+	ctx.PutPropString(index, goFunctionRefProp)
+	ctx.putStorageFinalizer(index)
+}
+
+// addStorage adds the value to the storage, releasing first the values of the
+// objects already finalized.
+func (ctx *Context) addStorage(v interface{}) unsafe.Pointer {
+	ctx.releaseStorage()
+	return ctx.storage.add(v)
+}
+
+// pushStorageFinalizer creates the finalizer of the objects referencing a
+// value of the storage, it is written in ECMAScript since the finalizers can
+// be called at any time, even while the heap is destroyed. The pointers of the
+// finalized objects are queued, to be released later by releaseStorage.
+func (ctx *Context) pushStorageFinalizer() {
+	ctx.PushGlobalStash()
+	ctx.PushObject()
+	ctx.PushArray()
+	ctx.PutPropString(-2, "pending")
+
+	ctx.Context.PevalString(storageFinalizerJS)
+	ctx.Dup(-2)
+	ctx.PushArray()
+	for i, key := range []string{goProxyPtrProp, goFunctionRefProp} {
+		ctx.PushString(key)
+		ctx.PutPropIndex(-2, uint(i))
+	}
+
+	ctx.PushString(goPrevFinalizerProp)
+	ctx.Call(3)
+
+	ctx.PutPropString(-3, storageFinalizerProp)
+	ctx.PutPropString(-2, storageStateProp)
+	ctx.Pop()
+}
+
+// putStorageFinalizer sets the storage finalizer to the object at the given
+// index, the previous finalizer, if any, is called after it.
+func (ctx *Context) putStorageFinalizer(index int) {
+	index = ctx.NormalizeIndex(index)
+	ctx.GetFinalizer(index)
+	if ctx.IsFunction(-1) {
+		ctx.PutPropString(index, goPrevFinalizerProp)
+	} else {
+		ctx.Pop()
+	}
+
+	ctx.PushGlobalStash()
+	ctx.GetPropString(-1, storageFinalizerProp)
+	ctx.SetFinalizer(index)
+	ctx.Pop()
+}
+
+// releaseStorage removes from the storage the values of the finalized objects.
+func (ctx *Context) releaseStorage() {
+	ctx.PushGlobalStash()
+	ctx.GetPropString(-1, storageStateProp)
+	ctx.GetPropString(-1, "pending")
+	ctx.PushArray()
+	ctx.PutPropString(-3, "pending")
+
+	length := ctx.GetLength(-1)
+	for i := 0; i < length; i++ {
+		ctx.GetPropIndex(-1, uint(i))
+		ctx.storage.delete(ctx.GetPointer(-1))
+		ctx.Pop()
+	}
+
+	ctx.PopN(3)
+}
+
+// DestroyHeap destroys the Duktape heap and releases the storage and the
+// external bytes.
+func (ctx *Context) DestroyHeap() {
+	ctx.Context.DestroyHeap()
+	ctx.storage.destroy()
+	ctx.externalBytes = nil
+}
+
+// storageFinalizerJS returns the storage finalizer, it should not create
+// closures, since could keep the object referenced, calling the finalizer again.
+const storageFinalizerJS = `(function (state, keys, prevKey) {
+	return function (obj) {
+		for (var i = 0; i < keys.length; i++) {
+			if (obj[keys[i]] !== undefined) {
+				state.pending.push(obj[keys[i]]);
+			}
+		}
+
+		if (typeof obj[prevKey] === 'function') {
+			obj[prevKey](obj);
+		}
+	};
+})`
+
+func (ctx *Context) getGoFunctionRef(index int) interface{} {
+	defer ctx.Pop()
+	ctx.GetPropString(index, goFunctionRefProp)
+	if !ctx.IsPointer(-1) {
+		return nil
+	}
+
+	return ctx.storage.get(ctx.GetPointer(-1))
+}
+
 func (ctx *Context) wrapFunction(f interface{}) func(ctx *duktape.Context) int {
 	tbaContext := ctx
+	fn := reflect.ValueOf(f)
+	info := types.get(fn.Type()).fn
+
 	return func(ctx *duktape.Context) int {
-		args := tbaContext.getFunctionArgs(f)
-		return tbaContext.callFunction(f, args)
+		if tbaContext.isInterrupted() {
+			return duktape.ErrRetError
+		}
+
+		args := tbaContext.getFunctionArgs(info)
+		return tbaContext.callFunction(fn, info, args)
 	}
 }
 
-func (ctx *Context) getFunctionArgs(f interface{}) []reflect.Value {
-	def := reflect.ValueOf(f).Type()
-	isVariadic := def.IsVariadic()
-	inCount := def.NumIn()
-
+func (ctx *Context) getFunctionArgs(f *funcInfo) []reflect.Value {
+	inCount := len(f.in)
 	top := ctx.GetTopIndex()
 
 	var args []reflect.Value
 	for index := 0; index <= top; index++ {
-		var t reflect.Type
-		if (index+1) < inCount || (index < inCount && !isVariadic) {
-			t = def.In(index)
-		} else if isVariadic {
-			t = def.In(inCount - 1).Elem()
-		}
-
-		args = append(args, ctx.getValueFromContext(index, t))
+		args = append(args, f.argConverter(index)(ctx, index))
 	}
 
 	//Optional args
@@ -389,11 +931,11 @@ func (ctx *Context) getFunctionArgs(f interface{}) []reflect.Value {
 	if inCount > argc {
 		for i := argc; i < inCount; i++ {
 			//Avoid send empty slice when variadic
-			if isVariadic && i-1 < inCount {
+			if f.isVariadic && i-1 < inCount {
 				break
 			}
 
-			args = append(args, reflect.Zero(def.In(i)))
+			args = append(args, reflect.Zero(f.in[i]))
 		}
 	}
 
@@ -409,6 +951,10 @@ func (ctx *Context) getValueFromContext(index int, t reflect.Type) reflect.Value
 		return ctx.getFunction(index, t)
 	}
 
+	if v, ok := ctx.getBytes(index, t); ok {
+		return v
+	}
+
 	return ctx.getValueUsingJSON(index, t)
 }
 
@@ -451,9 +997,10 @@ func (ctx *Context) wrapDuktapePointer(
 func (ctx *Context) getCallResult(t reflect.Type) []reflect.Value {
 	var result []reflect.Value
 
+	f := types.get(t).fn
 	oCount := t.NumOut()
 	if oCount == 1 {
-		result = append(result, ctx.getValueFromContext(-1, t.Out(0)))
+		result = append(result, f.outConv[0](ctx, -1))
 	} else if oCount > 1 {
 		if ctx.GetLength(-1) != oCount {
 			panic("Invalid count of return value on proxied function.")
@@ -462,7 +1009,7 @@ func (ctx *Context) getCallResult(t reflect.Type) []reflect.Value {
 		idx := ctx.NormalizeIndex(-1)
 		for i := 0; i < oCount; i++ {
 			ctx.GetPropIndex(idx, uint(i))
-			result = append(result, ctx.getValueFromContext(-1, t.Out(i)))
+			result = append(result, f.outConv[i](ctx, -1))
 		}
 	}
 
@@ -482,7 +1029,7 @@ func (ctx *Context) getProxyPtrProp(index int) unsafe.Pointer {
 func (ctx *Context) getValueUsingJSON(index int, t reflect.Type) reflect.Value {
 	v := reflect.New(t).Interface()
 
-	js := ctx.JsonEncode(index)
+	js := jsToUTF8(ctx.JsonEncode(index))
 	if len(js) == 0 {
 		return reflect.Zero(t)
 	}
@@ -495,10 +1042,10 @@ func (ctx *Context) getValueUsingJSON(index int, t reflect.Type) reflect.Value {
 	return reflect.ValueOf(v).Elem()
 }
 
-func (ctx *Context) callFunction(f interface{}, args []reflect.Value) int {
+func (ctx *Context) callFunction(fn reflect.Value, f *funcInfo, args []reflect.Value) int {
 	var err error
-	out := reflect.ValueOf(f).Call(args)
-	out, err = ctx.handleReturnError(out)
+	out := fn.Call(args)
+	out, err = ctx.handleReturnError(f, out)
 
 	if err != nil {
 		return duktape.ErrRetError
@@ -521,20 +1068,15 @@ func (ctx *Context) callFunction(f interface{}, args []reflect.Value) int {
 	return 1
 }
 
-func (ctx *Context) handleReturnError(out []reflect.Value) ([]reflect.Value, error) {
-	c := len(out)
-	if c == 0 {
+func (ctx *Context) handleReturnError(f *funcInfo, out []reflect.Value) ([]reflect.Value, error) {
+	if !f.returnError {
 		return out, nil
 	}
 
-	last := out[c-1]
-	if last.Type().Name() == "error" {
-		if !last.IsNil() {
-			return nil, last.Interface().(error)
-		}
-
-		return out[:c-1], nil
+	last := out[len(out)-1]
+	if !last.IsNil() {
+		return nil, last.Interface().(error)
 	}
 
-	return out, nil
+	return out[:len(out)-1], nil
 }
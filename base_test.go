@@ -1,6 +1,7 @@
 package candyjs
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"testing"
@@ -32,13 +33,13 @@ func (s *CandySuite) TestPushGlobalCandyJSObject(c *C) {
 	c.Assert(s.stored, Equals, "[object Object]")
 
 	c.Assert(s.ctx.PevalString(`store(CandyJS._call.toString())`), IsNil)
-	c.Assert(s.stored, Equals, "function anon() {/* ecmascript */}")
+	c.Assert(s.stored, Equals, "function () { [ecmascript code] }")
 
 	c.Assert(s.ctx.PevalString(`store(CandyJS.proxy.toString())`), IsNil)
-	c.Assert(s.stored, Equals, "function anon() {/* ecmascript */}")
+	c.Assert(s.stored, Equals, "function () { [ecmascript code] }")
 
 	c.Assert(s.ctx.PevalString(`store(CandyJS.require.toString())`), IsNil)
-	c.Assert(s.stored, Equals, "function anon() {/* native */}")
+	c.Assert(s.stored, Equals, "function () { [native code] }")
 }
 
 func (s *CandySuite) TestPushGlobalCandyJSObject_Require(c *C) {
@@ -60,6 +61,58 @@ func (s *CandySuite) TestSetRequireFunction(c *C) {
 	c.Assert(s.stored, Equals, "foo")
 }
 
+func (s *CandySuite) TestPutPropStringReadOnly(c *C) {
+	s.ctx.PushGlobalObject()
+	s.ctx.PushObject()
+	s.ctx.PushInt(42)
+	s.ctx.PutPropStringReadOnly(-2, "foo")
+	s.ctx.PutPropString(-2, "test")
+	s.ctx.Pop()
+
+	c.Assert(s.ctx.PevalString(`
+		test.foo = 21;
+		delete test.foo;
+		store([test.foo, Object.keys(test)])
+	`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{42.0, []interface{}{"foo"}})
+}
+
+func (s *CandySuite) TestPutPropStringLazy(c *C) {
+	var calls int
+	s.ctx.PushGlobalObject()
+	s.ctx.PutPropStringLazy(-1, "foo", func() {
+		calls++
+		s.ctx.PushInt(42)
+	})
+
+	s.ctx.PutPropStringLazy(-1, "bar", func() {
+		calls++
+		s.ctx.PushInt(42)
+	})
+
+	s.ctx.PutPropStringLazyReadOnly(-1, "qux", func() {
+		s.ctx.PushInt(42)
+	})
+	s.ctx.Pop()
+
+	c.Assert(s.ctx.PevalString(`
+		var keys = Object.keys(this).filter(function(k) { return k.length == 3 });
+		var values = [foo, foo];
+		bar = 21;
+		qux = 21;
+		store([keys, values, bar, qux])
+	`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{
+		[]interface{}{"foo", "bar", "qux"},
+		[]interface{}{42.0, 42.0},
+		21.0, 42.0,
+	})
+
+	c.Assert(calls, Equals, 1)
+}
+
 func (s *CandySuite) TestPushType(c *C) {
 	s.ctx.PushGlobalObject()
 	s.ctx.PushObject()
@@ -89,6 +142,66 @@ func (s *CandySuite) TestGlobalPushType(c *C) {
 	c.Assert(s.stored.(*MyStruct).Int, Equals, 42)
 }
 
+func (s *CandySuite) TestPushType_InitialValue(c *C) {
+	s.ctx.PushGlobalType("MyStruct", MyStruct{})
+	s.ctx.PushGlobalType("Duration", time.Duration(0))
+
+	c.Assert(s.ctx.PevalString(`
+		store([new MyStruct({int: 42}).int, new Duration(1000).string()])
+	`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{42.0, "1µs"})
+}
+
+func (s *CandySuite) TestPushType_ObjectLiteral(c *C) {
+	s.ctx.PushGlobalType("MyStruct", MyStruct{})
+	s.ctx.PushGlobalProxy("nested", &MyStruct{Int: 21})
+
+	c.Assert(s.ctx.PevalString(`
+		store(new MyStruct({int: 42, string: 'foo', nested: nested}))
+	`), IsNil)
+
+	v := s.stored.(*MyStruct)
+	c.Assert(v.Int, Equals, 42)
+	c.Assert(v.String, Equals, "foo")
+	c.Assert(v.Nested.Int, Equals, 21)
+
+	c.Assert(s.ctx.PevalString(`new MyStruct({qux: 42})`), NotNil)
+}
+
+func (s *CandySuite) TestPushType_Prototype(c *C) {
+	s.ctx.PushGlobalType("MyStruct", MyStruct{})
+	s.ctx.PushGlobalProxy("other", &MyStruct{Int: 3})
+
+	c.Assert(s.ctx.PevalString(`
+		var obj = new MyStruct({int: 2});
+		store([
+			obj instanceof MyStruct,
+			typeof MyStruct.prototype.multiply,
+			MyStruct.prototype.multiply.call(other, 2),
+			MyStruct.prototype.constructor === MyStruct
+		])
+	`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{true, "function", 6.0, true})
+}
+
+func (s *CandySuite) TestPushInterfaceType(c *C) {
+	s.ctx.PushGlobalObject()
+	s.ctx.PushInterfaceType((*fmt.Stringer)(nil))
+	s.ctx.PutPropString(-2, "Stringer")
+	s.ctx.Pop()
+	s.ctx.PushGlobalProxy("test", bytes.NewBufferString("foo"))
+
+	c.Assert(s.ctx.PevalString(`store(Stringer(test).string())`), IsNil)
+	c.Assert(s.stored, Equals, "foo")
+
+	c.Assert(s.ctx.PevalString(`
+		try { Stringer(42); } catch(err) { store(true); }
+	`), IsNil)
+	c.Assert(s.stored, Equals, true)
+}
+
 func (s *CandySuite) TestPushProxy(c *C) {
 	s.ctx.PushGlobalObject()
 	s.ctx.PushObject()
@@ -155,6 +268,20 @@ func (s *CandySuite) TestPushGlobalProxy_Nested(c *C) {
 	c.Assert(s.stored, DeepEquals, []interface{}{42.0, 84.0, 21.0, 63.0})
 }
 
+func (s *CandySuite) TestPushGlobalProxy_ReleaseStorage(c *C) {
+	s.ctx.PushGlobalProxy("test", &MyStruct{Int: 42})
+	size := s.ctx.storage.len()
+
+	c.Assert(s.ctx.PevalString(`
+		for (var i = 0; i < 10000; i++) {
+			test.multiply(2);
+		}
+	`), IsNil)
+
+	s.ctx.releaseStorage()
+	c.Assert(s.ctx.storage.len(), Equals, size)
+}
+
 func (s *CandySuite) TestPushGlobalProxy_Integration(c *C) {
 	now := time.Now()
 	after := now.Add(time.Millisecond)
@@ -270,6 +397,31 @@ func (s *CandySuite) TestPushGlobalGoFunction_String(c *C) {
 	c.Assert(called, Equals, "foo")
 }
 
+func (s *CandySuite) TestPushGlobalGoFunction_NonBMPString(c *C) {
+	type value struct {
+		String string `json:"string"`
+	}
+
+	var called []interface{}
+	s.ctx.PushGlobalGoFunction("test", func(
+		str string, i interface{}, b []byte, m map[string]string, v value, l []string,
+	) []interface{} {
+		called = []interface{}{str, i, string(b), m, v, l}
+		return called
+	})
+
+	c.Assert(s.ctx.PevalString(`
+		var s = "a😀";
+		var r = test(s, s, s, {foo: s}, {string: s}, [s]);
+		[r[0] == s, r[1] == s, r[3].foo == s, r[5][0] == s].join()
+	`), IsNil)
+	c.Assert(s.ctx.GetString(-1), Equals, "true,true,true,true")
+	c.Assert(called, DeepEquals, []interface{}{
+		"a😀", "a😀", "a😀", map[string]string{"foo": "a😀"},
+		value{String: "a😀"}, []string{"a😀"},
+	})
+}
+
 func (s *CandySuite) TestPushGlobalGoFunction_Int(c *C) {
 	var ri, ri8, ri16, ri32, ri64 interface{}
 	s.ctx.PushGlobalGoFunction("test_in_int", func(i int, i8 int8, i16 int16, i32 int32, i64 int64) {
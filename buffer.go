@@ -0,0 +1,138 @@
+package candyjs
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// ExternalBytes are pushed to JavaScript as an external buffer, pointing to
+// the same memory, instead of a copy. They should be read-only data, since
+// the changes made on any side are visible on the other. The plain buffers
+// cannot be finalized, so the bytes are kept referenced by the Context until
+// its heap is destroyed, pushing them repeatedly on a long lived Context keeps
+// growing its memory. The Contexts of a Pool are discarded when returned after
+// pushing external bytes.
+type ExternalBytes []byte
+
+var externalBytesType = reflect.TypeOf(ExternalBytes{})
+
+// PushBytes push a copy of the given bytes to the stack as a plain buffer,
+// behaving as an Uint8Array on JavaScript.
+func (ctx *Context) PushBytes(b []byte) {
+	ptr := ctx.PushFixedBuffer(len(b))
+	copy(unsafe.Slice((*byte)(ptr), len(b)), b)
+}
+
+// PushExternalBytes like PushBytes but the buffer points to the given bytes,
+// see ExternalBytes.
+func (ctx *Context) PushExternalBytes(b []byte) {
+	ctx.PushExternalBuffer()
+	if len(b) == 0 {
+		return
+	}
+
+	ctx.externalBytes = append(ctx.externalBytes, b)
+	ctx.ConfigBuffer(-1, b)
+}
+
+// pushBytes pushes a value of a []byte type, as a string if the ByteStrings
+// option is enabled.
+func (ctx *Context) pushBytes(v reflect.Value) {
+	switch {
+	case ctx.byteStrings:
+		ctx.PushString(string(v.Bytes()))
+	case v.Type() == externalBytesType:
+		ctx.PushExternalBytes(v.Bytes())
+	default:
+		ctx.PushBytes(v.Bytes())
+	}
+}
+
+// getBytes returns the buffer, typed array or string at the given index as a
+// value of the given type, when is a []byte type, or as a []byte for the
+// empty interface.
+func (ctx *Context) getBytes(index int, t reflect.Type) (reflect.Value, bool) {
+	switch {
+	case isBytes(t):
+		if ctx.IsString(index) {
+			return reflect.ValueOf([]byte(jsToUTF8(ctx.GetString(index)))).Convert(t), true
+		}
+
+		if b, ok := ctx.getBuffer(index); ok {
+			return reflect.ValueOf(b).Convert(t), true
+		}
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		if b, ok := ctx.getBuffer(index); ok {
+			return reflect.ValueOf(b), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// getBuffer returns a copy of the bytes of the buffer at the given index, a
+// plain buffer, an ArrayBuffer or any of its views, like an Uint8Array.
+func (ctx *Context) getBuffer(index int) ([]byte, bool) {
+	var b []byte
+	ok := ctx.bufferData(index, func(data []byte) {
+		b = append([]byte{}, data...)
+	})
+
+	return b, ok
+}
+
+// isBuffer returns if the value at the given index is accepted by getBuffer.
+func (ctx *Context) isBuffer(index int) bool {
+	return ctx.bufferData(index, func([]byte) {})
+}
+
+// bufferData calls f with the bytes of the buffer at the given index, the
+// bytes are only valid during the call.
+func (ctx *Context) bufferData(index int, f func([]byte)) bool {
+	if ctx.IsBuffer(index) {
+		ptr, size := ctx.GetBuffer(index)
+		f(unsafe.Slice((*byte)(ptr), size))
+		return true
+	}
+
+	if !ctx.IsObject(index) || ctx.getProxy(index) != nil {
+		return false
+	}
+
+	index = ctx.NormalizeIndex(index)
+	ctx.PushGlobalObject()
+	ctx.GetPropString(-1, "CandyJS")
+	defer ctx.Pop3()
+
+	ctx.PushString("_bufferData")
+	ctx.Dup(index)
+	if ctx.PcallProp(-3, 1) != 0 || !ctx.IsArray(-1) {
+		return false
+	}
+
+	ctx.GetPropIndex(-1, 0)
+	ctx.GetPropIndex(-2, 1)
+	ctx.GetPropIndex(-3, 2)
+	defer ctx.Pop3()
+
+	ptr, _ := ctx.GetBuffer(-3)
+	offset, length := ctx.GetInt(-2), ctx.GetInt(-1)
+	f(unsafe.Slice((*byte)(ptr), offset+length)[offset:])
+	return true
+}
+
+const bufferDataJS = `CandyJS._bufferData = function(v) {
+	if (v instanceof ArrayBuffer) {
+		v = new Uint8Array(v);
+	}
+
+	if (!ArrayBuffer.isView(v)) {
+		return;
+	}
+
+	return [Uint8Array.plainOf(v), v.byteOffset, v.byteLength];
+}`
+
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
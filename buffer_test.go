@@ -0,0 +1,72 @@
+package candyjs
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestPushInterface_Bytes(c *C) {
+	s.ctx.PushGlobalInterface("foo", []byte{0, 255, 'a'})
+	c.Assert(s.ctx.PevalString(`
+		[foo instanceof Uint8Array, foo.length, foo[1], foo[2]].join()
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "true,3,255,97")
+}
+
+func (s *CandySuite) TestPushInterface_ExternalBytes(c *C) {
+	b := []byte("foo")
+	s.ctx.PushGlobalInterface("foo", ExternalBytes(b))
+	b[0] = 'b'
+
+	c.Assert(s.ctx.PevalString(`foo[0]`), IsNil)
+	c.Assert(s.ctx.GetInt(-1), Equals, int('b'))
+}
+
+func (s *CandySuite) TestPushInterface_ByteStrings(c *C) {
+	ctx := NewContextWithOptions(Options{ByteStrings: true})
+	defer ctx.DestroyHeap()
+
+	ctx.PushGlobalInterface("foo", []byte("bar"))
+	c.Assert(ctx.PevalString(`typeof foo + ':' + foo`), IsNil)
+	c.Assert(ctx.GetString(-1), Equals, "string:bar")
+}
+
+func (s *CandySuite) TestPushGoFunction_Bytes(c *C) {
+	var got [][]byte
+	s.ctx.PushGlobalGoFunction("foo", func(b []byte) {
+		got = append(got, b)
+	})
+
+	c.Assert(s.ctx.PevalString(`
+		foo(new Uint8Array([0, 255]));
+		foo(new Uint8Array([1, 2, 3, 4]).subarray(1, 3));
+		foo(new Uint8Array([5, 6]).buffer);
+		foo(new DataView(new Uint8Array([7]).buffer));
+		foo(Uint8Array.plainOf(new Uint8Array([8])));
+		foo('qux');
+	`), IsNil)
+
+	c.Assert(got, DeepEquals, [][]byte{
+		{0, 255}, {2, 3}, {5, 6}, {7}, {8}, []byte("qux"),
+	})
+}
+
+func (s *CandySuite) TestProxy_SetBytes(c *C) {
+	m := &MyStruct{}
+	s.ctx.PushGlobalProxy("m", m)
+
+	c.Assert(s.ctx.PevalString(`m.bytes = new Uint8Array([1, 2])`), IsNil)
+	c.Assert(m.Bytes, DeepEquals, []byte{1, 2})
+
+	c.Assert(s.ctx.PevalString(`m.bytes = 'foo'`), IsNil)
+	c.Assert(m.Bytes, DeepEquals, []byte("foo"))
+
+	c.Assert(s.ctx.PevalString(`m.bytes[0]`), IsNil)
+	c.Assert(s.ctx.GetInt(-1), Equals, int('f'))
+}
+
+func (s *CandySuite) TestInspect_Buffer(c *C) {
+	s.ctx.PushGlobalInterface("foo", []byte{1, 2, 3})
+	c.Assert(s.ctx.PevalString(`foo`), IsNil)
+	c.Assert(s.ctx.Inspect(-1), Equals, "Uint8Array(3) [ 1, 2, 3 ]")
+}
@@ -14,6 +14,12 @@ func main() {
 		&CmdImport{},
 	)
 
+	parser.AddCommand(
+		"repl",
+		"Starts an interactive JavaScript console", "",
+		&CmdRepl{},
+	)
+
 	_, err := parser.Parse()
 	if err != nil {
 		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrCommandRequired {
@@ -2,43 +2,114 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"text/template"
+
+	"golang.org/x/tools/go/packages"
 )
 
 // CmdImport generates a new candyjs.PackagePusher function for the given
-// Package, the package can be any builtin or any other third party one.
+// Package, the package can be any builtin or any other third party one, a
+// pattern like ./... imports every package of a module.
 type CmdImport struct {
-	Output string `short:"" long:"output" description:"output file name" default:"pkg_%s.go"`
-	Debug  bool   `short:"" long:"debug" description:"active debug messages"`
-	Args   struct {
-		Package string `positional-arg-name:"package" description:"package to import"`
+	Output      string   `short:"" long:"output" description:"output file name" default:"pkg_%s.go"`
+	Tags        string   `short:"" long:"tags" description:"comma-separated list of build tags"`
+	Include     []string `short:"" long:"include" description:"glob of the identifiers to bind, can be repeated"`
+	Exclude     []string `short:"" long:"exclude" description:"glob of the identifiers to skip, can be repeated"`
+	Manifest    string   `short:"" long:"manifest" description:"YAML or JSON file with the symbols to bind, renames and read-only flags"`
+	Instantiate []string `short:"" long:"instantiate" description:"instantiation of a generic function, like 'Contains[[]string, string]', can be repeated"`
+	Lazy        bool     `short:"" long:"lazy" description:"push every object the first time is used"`
+	Static      bool     `short:"" long:"static" description:"push the functions with basic types without reflection"`
+	Dts         string   `short:"" long:"dts" description:"writes also a TypeScript declaration file" optional:"yes" optional-value:"%s.d.ts"`
+	Debug       bool     `short:"" long:"debug" description:"active debug messages"`
+	Args        struct {
+		Package string `positional-arg-name:"package" description:"package to import, or a pattern like ./..."`
 	} `positional-args:"yes" required:"true"`
 
-	curPkgName, fullPkgName, pkgName string
+	curPkgName, fullPkgName, pkgName, fileName string
 }
 
 // Execute run the CmdImport, follows the go-flags interface
 func (c *CmdImport) Execute(args []string) error {
-	c.fullPkgName = c.Args.Package
 	fmt.Printf("Processing %q\n", c.Args.Package)
 
-	objects, err := c.getObjects()
+	pkgs, err := c.loadPackages()
 	if err != nil {
 		return err
 	}
 
+	multiple := len(pkgs) > 1
+	if multiple && (c.Manifest != "" || len(c.Instantiate) != 0) {
+		return errors.New("--manifest and --instantiate require a single package")
+	}
+
 	c.getCurrentPckgName()
-	return c.render(objects)
+	for _, pkg := range pkgs {
+		if err := c.importPackage(pkg, multiple); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importPackage generates the files of the given package, when importing
+// several packages the ones that cannot be bound are skipped.
+func (c *CmdImport) importPackage(pkg *packages.Package, multiple bool) error {
+	c.fullPkgName, c.pkgName, c.fileName = pkg.PkgPath, pkg.Name, pkg.Name
+	if multiple {
+		c.fileName = getFileName(pkg)
+	}
+
+	err := checkPackage(pkg)
+	if err == nil {
+		var objects *packageObjects
+		if objects, err = c.getObjects(pkg); err == nil {
+			return c.renderFiles(objects)
+		}
+	}
+
+	if multiple && errors.Is(err, errNotBindable) {
+		fmt.Printf("Skipped package %s\n", err)
+		return nil
+	}
+
+	return err
+}
+
+func (c *CmdImport) renderFiles(objects *packageObjects) error {
+	if err := c.render(objects); err != nil {
+		return err
+	}
+
+	if c.Dts == "" {
+		return nil
+	}
+
+	return c.renderDts(objects)
+}
+
+// getFileName returns a name for the files of a package, unique between the
+// packages of a module, based on its path.
+func getFileName(pkg *packages.Package) string {
+	name := pkg.PkgPath
+	if pkg.Module != nil {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, pkg.Module.Path), "/")
+	}
+
+	if name == "" {
+		return pkg.Name
+	}
+
+	return strings.Replace(name, "/", "_", -1)
 }
 
 func (c *CmdImport) getCurrentPckgName() {
@@ -48,91 +119,144 @@ func (c *CmdImport) getCurrentPckgName() {
 	}
 }
 
-func (c *CmdImport) getObjects() (map[string]*ast.Object, error) {
-	pkgs, err := c.parserPackage()
+func (c *CmdImport) getObjects(pkg *packages.Package) (*packageObjects, error) {
+	if c.Debug {
+		for _, filename := range pkg.GoFiles {
+			fmt.Printf("Processed package file %q\n", filename)
+		}
+	}
+
+	m, err := c.getManifest()
 	if err != nil {
 		return nil, err
 	}
 
-	var objects map[string]*ast.Object
-	for _, pkg := range pkgs {
-		pkgObjs := c.getPackageObjects(pkg)
-		if pkg.Name == "main" || len(pkgObjs) == 0 {
-			continue
-		}
+	objects := getPackageObjects(pkg.Types)
+	if err := objects.instantiate(pkg.Types, c.pkgName, c.Instantiate); err != nil {
+		return nil, err
+	}
 
-		c.pkgName = pkg.Name
-		objects = pkgObjs
+	if err := m.filter(objects); err != nil {
+		return nil, err
 	}
 
-	return objects, nil
-}
+	for _, s := range objects.Skipped {
+		fmt.Printf("Skipped %s: %s\n", s.Name, s.Reason)
+	}
 
-func (c *CmdImport) parserPackage() (map[string]*ast.Package, error) {
-	dir, err := c.getPackagePath(c.fullPkgName)
-	if err != nil {
-		return nil, err
+	if c.Static {
+		c.setStaticWrappers(objects)
 	}
 
-	return parser.ParseDir(token.NewFileSet(), dir, nil, 0)
-}
+	objects.attachStatics()
+
+	if len(objects.Objects) == 0 {
+		return nil, fmt.Errorf("%w: %q has no objects that can be bound", errNotBindable, c.fullPkgName)
+	}
 
-func (c *CmdImport) getPackageObjects(pkg *ast.Package) map[string]*ast.Object {
-	objects := make(map[string]*ast.Object)
+	return objects, nil
+}
 
-	for filename, f := range pkg.Files {
-		if strings.HasSuffix(filepath.Base(filename), "_test.go") {
+func (c *CmdImport) setStaticWrappers(objs *packageObjects) {
+	for _, o := range objs.Objects {
+		if o.Kind != kindFunc || len(o.Instances) > 1 {
 			continue
 		}
 
-		for name, object := range f.Scope.Objects {
-			if ast.IsExported(name) {
-				objects[name] = object
-			}
+		o.Static = staticWrapper(c.pkgName, o)
+		if o.Static == "" && c.Debug {
+			fmt.Printf("Function %s pushed using reflection\n", o.Name)
 		}
+	}
+}
 
-		if c.Debug {
-			fmt.Printf("Processed package file %q\n", filename)
+func (c *CmdImport) getManifest() (*manifest, error) {
+	m := &manifest{}
+	if c.Manifest != "" {
+		var err error
+		if m, err = readManifest(c.Manifest); err != nil {
+			return nil, err
 		}
 	}
 
-	return objects
+	m.Include = append(m.Include, c.Include...)
+	m.Exclude = append(m.Exclude, c.Exclude...)
+	return m, m.validate()
 }
 
-func (c *CmdImport) getPackagePath(pkgName string) (string, error) {
-	for _, base := range []string{os.Getenv("GOPATH"), runtime.GOROOT()} {
-		dir := filepath.Join(base, "src", pkgName)
-		_, err := os.Stat(dir)
-		if err == nil {
-			return dir, nil
+// errNotBindable is returned for the packages that cannot be imported
+var errNotBindable = errors.New("not bindable")
+
+// loadPackages resolves the packages in the same way as the go command does,
+// honouring go.mod, vendor directories, replace directives and build tags.
+// Patterns like `./...` can be used to import every package of a module.
+func (c *CmdImport) loadPackages() ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedTypes |
+			packages.NeedModule,
+	}
+
+	if c.Tags != "" {
+		cfg.BuildFlags = []string{"-tags", c.Tags}
+	}
+
+	pkgs, err := packages.Load(cfg, c.Args.Package)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("package %q not found", c.Args.Package)
+	}
+
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) != 0 {
+			return nil, fmt.Errorf("package %q: %s", pkg.PkgPath, pkg.Errors[0])
 		}
 	}
 
-	return "", fmt.Errorf("package %q not found", pkgName)
+	return pkgs, nil
+}
+
+// checkPackage returns an error if the package cannot be imported, because is
+// a command or is the package where the pushers are generated.
+func checkPackage(pkg *packages.Package) error {
+	if pkg.Name == "main" {
+		return fmt.Errorf("%w: %q is a command", errNotBindable, pkg.PkgPath)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if len(pkg.GoFiles) != 0 && filepath.Dir(pkg.GoFiles[0]) == wd {
+		return fmt.Errorf("%w: %q is the current package", errNotBindable, pkg.PkgPath)
+	}
+
+	return nil
 }
 
-func (c *CmdImport) render(objs map[string]*ast.Object) error {
+func (c *CmdImport) render(objs *packageObjects) error {
 	t := template.New("tmpl")
 	t.Funcs(template.FuncMap{
-		"isFunc":           isFunc,
-		"isVar":            isVar,
-		"isConst":          isConst,
-		"isStruct":         isStruct,
-		"nameToJavaScript": nameToJavaScript,
+		"pkg": func() string { return c.pkgName },
 	})
 
-	_, err := t.Parse(formatTemplateNewLines(tmpl))
+	_, err := t.Parse(tmpl)
 	if err != nil {
 		return err
 	}
 
 	buf := bytes.NewBuffer(nil)
 	err = t.Execute(buf, struct {
-		FullPkgName, CurPkgName, PkgName, Objs interface{}
+		FullPkgName, CurPkgName string
+		Lazy                    bool
+		Objs                    *packageObjects
 	}{
 		FullPkgName: c.fullPkgName,
 		CurPkgName:  c.curPkgName,
-		PkgName:     c.pkgName,
+		Lazy:        c.Lazy,
 		Objs:        objs,
 	})
 
@@ -145,38 +269,31 @@ func (c *CmdImport) render(objs map[string]*ast.Object) error {
 		return err
 	}
 
-	file := fmt.Sprintf(c.Output, c.pkgName)
+	file := outputFile(c.Output, c.fileName)
 	fmt.Printf("File generated %q\n", file)
 
 	return ioutil.WriteFile(file, output, 0644)
 }
 
-func formatTemplateNewLines(tmpl string) string {
-	return strings.Replace(tmpl, "\\\n", " ", -1)
-}
-
-func isFunc(obj *ast.Object) bool {
-	return obj.Kind == ast.Fun
-}
+func (c *CmdImport) renderDts(objs *packageObjects) error {
+	output := newDtsWriter(objs).write(c.fullPkgName, objs)
 
-func isVar(obj *ast.Object) bool {
-	return obj.Kind == ast.Var
-}
+	file := outputFile(c.Dts, c.fileName)
+	fmt.Printf("File generated %q\n", file)
 
-func isConst(obj *ast.Object) bool {
-	return obj.Kind == ast.Con
+	return ioutil.WriteFile(file, []byte(output), 0644)
 }
 
-func isStruct(obj *ast.Object) bool {
-	if obj.Kind != ast.Typ {
-		return false
+// outputFile replaces the %s of the given file name by the package name
+func outputFile(pattern, pkgName string) string {
+	if !strings.Contains(pattern, "%s") {
+		return pattern
 	}
 
-	_, isStruct := obj.Decl.(*ast.TypeSpec).Type.(*ast.StructType)
-	return isStruct
+	return fmt.Sprintf(pattern, pkgName)
 }
 
-//TODO: is copy pasted from the main package
+// TODO: is copy pasted from the main package
 func nameToJavaScript(name string) string {
 	var toLower, keep string
 	for _, c := range name {
@@ -199,7 +316,8 @@ func nameToJavaScript(name string) string {
 
 const tmpl = `
 {{$fullPkg := .FullPkgName}}
-{{$pkg := .PkgName}}
+// Code generated by candyjs import; DO NOT EDIT.
+
 package {{.CurPkgName}}
 
 import (
@@ -211,23 +329,67 @@ import (
 func init() {
 	candyjs.RegisterPackagePusher("{{$fullPkg}}", func(ctx *candyjs.Context) {
 		ctx.PushObject()
-		{{range .Objs}} \
-		{{if isFunc .}} \
-			ctx.PushGoFunction({{$pkg}}.{{.Name}})
-			ctx.PutPropString(-2, "{{nameToJavaScript .Name}}")
-		{{else if isStruct .}} \
-			ctx.PushType({{$pkg}}.{{.Name}}{})
-			ctx.PutPropString(-2, "{{.Name}}")
-		{{else if isVar .}} \
-			ctx.PushProxy({{$pkg}}.{{.Name}})
-			ctx.PutPropString(-2, "{{.Name}}")
-		{{else if isConst .}} \
-			ctx.PushInterface({{$pkg}}.{{.Name}})
-			ctx.PutPropString(-2, "{{.Name}}")
-		{{else}} \
-			//ignored {{.Name}} - {{.Kind}}
-		{{end}} \
-		{{end}} \
+		{{- range $i, $o := .Objs.Objects}}
+		{{- if $i}}
+		{{end}}
+		{{- if $.Lazy}}
+		ctx.PutPropStringLazy{{if .ReadOnly}}ReadOnly{{end}}(-1, "{{.JSName}}", func() {
+			{{- template "push" .}}
+		})
+		{{- else}}
+			{{- template "push" .}}
+		ctx.PutPropString{{if .ReadOnly}}ReadOnly{{end}}(-2, "{{.JSName}}")
+		{{- end}}
+		{{- end}}
+		{{- if .Objs.Skipped}}
+		{{end}}
+		{{- range .Objs.Skipped}}
+		//skipped {{.Name}}: {{.Reason}}
+		{{- end}}
 	})
 }
+
+{{define "push"}}
+{{- if eq .Kind "func"}}
+	{{- template "func" .}}
+{{- else if eq .Kind "type"}}
+	ctx.PushType(*new({{pkg}}.{{.Name}}))
+	{{- range .Consts}}
+	{{- template "rounded" .}}
+	ctx.PushInterface({{.Expr pkg}})
+	ctx.PutPropString(-2, "{{.Name}}")
+	{{- end}}
+	{{- range .Statics}}
+		{{- template "func" .}}
+	ctx.PutPropString(-2, "{{.JSName}}")
+	{{- end}}
+{{- else if eq .Kind "interface"}}
+	ctx.PushInterfaceType((*{{pkg}}.{{.Name}})(nil))
+{{- else if eq .Kind "var"}}
+	{{- if and .Ref (not .ReadOnly)}}
+	ctx.PushProxy(&{{pkg}}.{{.Name}})
+	{{- else}}
+	ctx.PushInterface({{pkg}}.{{.Name}})
+	{{- end}}
+{{- else if eq .Kind "const"}}
+	{{- template "rounded" .}}
+	ctx.PushInterface({{.Expr pkg}})
+{{- end}}
+{{- end}}
+
+{{define "rounded"}}
+{{- if .Rounded}}
+	// {{.Name}} is out of the safe integer range, the pushed value is rounded
+{{- end}}
+{{- end}}
+
+{{define "func"}}
+{{- if gt (len .Instances) 1}}
+	ctx.PushGenericFunction({{range $i, $e := .Instances}}{{if $i}}, {{end}}{{$e.Expr}}{{end}})
+{{- else if .Static}}
+	ctx.PushStaticFunction({{.Func pkg}}, {{.Static}})
+{{- else}}
+	ctx.PushGoFunction({{.Func pkg}})
+{{- end}}
+{{- end}}
 `
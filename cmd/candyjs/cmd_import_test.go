@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// sampleDir is the directory of the sample module, where the imports are run.
+const sampleDir = "testdata/sample/pushers"
+
+func (s *CmdSuite) TestImport(c *C) {
+	s.assertImport(c, "import", &CmdImport{}, "example.com/sample")
+}
+
+func (s *CmdSuite) TestImport_IncludeExclude(c *C) {
+	s.assertImport(c, "include", &CmdImport{
+		Include: []string{"*Point", "S*"},
+		Exclude: []string{"Shape"},
+	}, "example.com/sample")
+}
+
+func (s *CmdSuite) TestImport_Manifest(c *C) {
+	s.assertImport(c, "manifest", &CmdImport{Manifest: "../manifest.yaml"}, "example.com/sample")
+}
+
+func (s *CmdSuite) TestImport_Dts(c *C) {
+	s.assertImport(c, "dts", &CmdImport{Dts: "%s.d.ts"}, "example.com/sample")
+}
+
+func (s *CmdSuite) TestImport_Static(c *C) {
+	s.assertImport(c, "static", &CmdImport{Static: true}, "example.com/sample")
+}
+
+func (s *CmdSuite) TestImport_Instantiate(c *C) {
+	s.assertImport(c, "instantiate", &CmdImport{
+		Instantiate: []string{"Max[int]", "Max[float64]"},
+	}, "example.com/sample")
+}
+
+func (s *CmdSuite) TestImport_Lazy(c *C) {
+	s.assertImport(c, "lazy", &CmdImport{Lazy: true}, "example.com/sample")
+}
+
+func (s *CmdSuite) TestImport_Module(c *C) {
+	s.assertImport(c, "module", &CmdImport{}, "example.com/sample/...")
+}
+
+// assertImport runs the command in the sample module, comparing the generated
+// files with the ones at testdata/golden/<name>, if -update is given the
+// golden files are written instead.
+func (s *CmdSuite) assertImport(c *C, name string, cmd *CmdImport, pkg string) {
+	golden, err := filepath.Abs(filepath.Join("testdata", "golden", name))
+	c.Assert(err, IsNil)
+
+	output := c.MkDir()
+	cmd.Output = filepath.Join(output, "pkg_%s.go")
+	if cmd.Dts != "" {
+		cmd.Dts = filepath.Join(output, cmd.Dts)
+	}
+
+	cmd.Args.Package = pkg
+
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	c.Assert(os.Chdir(sampleDir), IsNil)
+	defer os.Chdir(wd)
+
+	c.Assert(cmd.Execute(nil), IsNil)
+
+	files, err := ioutil.ReadDir(output)
+	c.Assert(err, IsNil)
+
+	if *update {
+		c.Assert(os.RemoveAll(golden), IsNil)
+		c.Assert(os.MkdirAll(golden, 0755), IsNil)
+	}
+
+	var names []string
+	for _, f := range files {
+		content, err := ioutil.ReadFile(filepath.Join(output, f.Name()))
+		c.Assert(err, IsNil)
+
+		filename := filepath.Join(golden, f.Name()+".golden")
+		names = append(names, f.Name()+".golden")
+		if *update {
+			c.Assert(ioutil.WriteFile(filename, content, 0644), IsNil)
+			continue
+		}
+
+		expected, err := ioutil.ReadFile(filename)
+		c.Assert(err, IsNil)
+		c.Assert(string(content), Equals, string(expected), Commentf(filename))
+	}
+
+	expected, err := ioutil.ReadDir(golden)
+	c.Assert(err, IsNil)
+	c.Assert(expected, HasLen, len(names))
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/mcuadros/go-candyjs"
+	_ "github.com/mcuadros/go-candyjs/cmd/candyjs/pushers"
+	"github.com/peterh/liner"
+)
+
+const (
+	replPrompt         = "> "
+	replContinuePrompt = "... "
+	replHistoryFile    = ".candyjs_history"
+)
+
+// CmdRepl starts an interactive JavaScript console on a new Context, the
+// packages registered on the binary can be pushed as globals. The fmt,
+// strconv, strings and time packages are registered by the pushers package,
+// any other requires add its pusher, generated by `candyjs import`, to it.
+type CmdRepl struct {
+	Require []string `short:"r" long:"require" description:"package to push as a global, as 'package' or 'package:alias'"`
+	History string   `short:"" long:"history" description:"history file, by default ~/.candyjs_history"`
+
+	ctx  *candyjs.Context
+	line *liner.State
+}
+
+// Execute run the CmdRepl, follows the go-flags interface
+func (c *CmdRepl) Execute(args []string) error {
+	c.ctx = candyjs.NewContext()
+	if err := c.pushPackages(); err != nil {
+		return err
+	}
+
+	c.line = liner.NewLiner()
+	defer c.line.Close()
+
+	c.line.SetCtrlCAborts(true)
+	c.loadHistory()
+	defer c.saveHistory()
+
+	return c.loop()
+}
+
+func (c *CmdRepl) pushPackages() error {
+	for _, pkg := range c.Require {
+		alias := path.Base(pkg)
+		if i := strings.LastIndex(pkg, ":"); i != -1 {
+			pkg, alias = pkg[:i], pkg[i+1:]
+		}
+
+		if err := c.ctx.PushGlobalPackage(pkg, alias); err != nil {
+			return fmt.Errorf("%s: %q", err, pkg)
+		}
+	}
+
+	return nil
+}
+
+func (c *CmdRepl) loop() error {
+	var src string
+	for {
+		prompt := replPrompt
+		if src != "" {
+			prompt = replContinuePrompt
+		}
+
+		input, err := c.line.Prompt(prompt)
+		if err == liner.ErrPromptAborted {
+			src = ""
+			continue
+		}
+
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if src == "" && strings.HasPrefix(strings.TrimSpace(input), ".") {
+			if exit := c.command(strings.TrimSpace(input)); exit {
+				return nil
+			}
+
+			continue
+		}
+
+		src += input + "\n"
+		if isIncomplete(src) {
+			continue
+		}
+
+		c.line.AppendHistory(strings.TrimSpace(src))
+		c.eval(src)
+		src = ""
+	}
+}
+
+func (c *CmdRepl) command(input string) (exit bool) {
+	fields := strings.Fields(input)
+	switch fields[0] {
+	case ".exit":
+		return true
+	case ".load":
+		if len(fields) != 2 {
+			fmt.Println("usage: .load file.js")
+			return false
+		}
+
+		c.line.AppendHistory(input)
+		c.print(c.ctx.PevalFile(fields[1]))
+	case ".help":
+		fmt.Println(".load file.js  evaluates the given file on the current context")
+		fmt.Println(".exit          exits the repl")
+		fmt.Println(".help          prints this help")
+	default:
+		fmt.Printf("unknown command %q, try .help\n", fields[0])
+	}
+
+	return false
+}
+
+func (c *CmdRepl) eval(src string) {
+	c.print(c.ctx.PevalString(src))
+}
+
+func (c *CmdRepl) print(err error) {
+	defer c.ctx.Pop()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(c.ctx.Inspect(-1))
+}
+
+func (c *CmdRepl) historyFile() string {
+	if c.History != "" {
+		return c.History
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, replHistoryFile)
+}
+
+func (c *CmdRepl) loadHistory() {
+	f, err := os.Open(c.historyFile())
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+	c.line.ReadHistory(f)
+}
+
+func (c *CmdRepl) saveHistory() {
+	file := c.historyFile()
+	if file == "" {
+		return
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+	c.line.WriteHistory(f)
+}
+
+var errUnbalanced = errors.New("unbalanced")
+
+// isIncomplete returns true when the source has unclosed brackets, strings or
+// comments, or ends with a backslash, meaning that more lines are expected.
+// The brackets inside strings, comments and regular expressions are ignored.
+func isIncomplete(src string) bool {
+	if strings.HasSuffix(strings.TrimRight(src, "\n"), "\\") {
+		return true
+	}
+
+	depth, err := bracketsDepth(src)
+	return err == nil && depth > 0
+}
+
+func bracketsDepth(src string) (int, error) {
+	var depth int
+	var quote, prev rune
+	var escaped, lineComment, blockComment, regexp, class bool
+
+	runes := []rune(src)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		inComment := lineComment || blockComment
+
+		switch {
+		case lineComment:
+			lineComment = r != '\n'
+		case blockComment:
+			if r == '*' && next == '/' {
+				blockComment = false
+				i++
+			}
+		case regexp:
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '[':
+				class = true
+			case r == ']':
+				class = false
+			case r == '\n' || (r == '/' && !class):
+				regexp, class = false, false
+			}
+		case quote != 0:
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == quote || (r == '\n' && quote != '`') {
+				quote = 0
+			}
+		case r == '/' && next == '/':
+			lineComment = true
+			i++
+		case r == '/' && next == '*':
+			blockComment = true
+			i++
+		case r == '/' && isRegexpStart(runes[:i], prev):
+			regexp = true
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+			if depth < 0 {
+				return depth, errUnbalanced
+			}
+		}
+
+		if !inComment && !lineComment && !blockComment && !unicode.IsSpace(r) {
+			prev = r
+		}
+	}
+
+	if blockComment || quote == '`' {
+		depth++
+	}
+
+	return depth, nil
+}
+
+// regexpKeywords are the keywords that can be followed by a regular expression
+var regexpKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true,
+}
+
+// isRegexpStart returns true if a slash after the given source starts a
+// regular expression instead of being a division, based on the previous
+// character not being a space or part of a comment.
+func isRegexpStart(src []rune, prev rune) bool {
+	switch {
+	case prev == 0:
+		return true
+	case strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", prev):
+		return true
+	case !isIdentifierRune(prev):
+		return false
+	}
+
+	src = []rune(strings.TrimRightFunc(string(src), unicode.IsSpace))
+	start := len(src)
+	for start > 0 && isIdentifierRune(src[start-1]) {
+		start--
+	}
+
+	return regexpKeywords[string(src[start:])]
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/mcuadros/go-candyjs"
+	. "gopkg.in/check.v1"
+)
+
+func (s *CmdSuite) TestBracketsDepth(c *C) {
+	for _, t := range []struct {
+		src   string
+		depth int
+		err   error
+	}{
+		{`foo(`, 1, nil},
+		{`foo({a: [1`, 3, nil},
+		{`foo({a: [1]})`, 0, nil},
+		{`foo)`, -1, errUnbalanced},
+		{`"(" + '[' + "\"{"`, 0, nil},
+		{"`${foo}(", 1, nil},
+		{"`foo\n", 1, nil},
+		{"foo( // )\n", 1, nil},
+		{`foo( /* ) */`, 1, nil},
+		{`/* ( `, 1, nil},
+		{`/* ( */ foo`, 0, nil},
+		{`foo(/\)/`, 1, nil},
+		{`foo(/[)]/.test(a)`, 1, nil},
+		{`x = /(/; y = [`, 1, nil},
+		{`return /{/.test(a)`, 0, nil},
+		{`a / (b`, 1, nil},
+		{`a /* c */ / (b`, 1, nil},
+		{`(a) / (b`, 1, nil},
+		{`"a" / (b`, 1, nil},
+	} {
+		depth, err := bracketsDepth(t.src)
+		c.Assert(depth, Equals, t.depth, Commentf(t.src))
+		c.Assert(err, Equals, t.err, Commentf(t.src))
+	}
+}
+
+func (s *CmdSuite) TestIsIncomplete(c *C) {
+	c.Assert(isIncomplete("foo(\n"), Equals, true)
+	c.Assert(isIncomplete("foo + \\\n"), Equals, true)
+	c.Assert(isIncomplete("foo()\n"), Equals, false)
+	c.Assert(isIncomplete("foo())\n"), Equals, false)
+}
+
+func (s *CmdSuite) TestPushPackages(c *C) {
+	cmd := &CmdRepl{Require: []string{"strings", "strconv:conv", "time"}}
+	cmd.ctx = candyjs.NewContext()
+	defer cmd.ctx.DestroyHeap()
+
+	c.Assert(cmd.pushPackages(), IsNil)
+	c.Assert(cmd.ctx.PevalString(`
+		[strings.toUpper("foo"), conv.itoa(42), typeof time.now].join()
+	`), IsNil)
+	c.Assert(cmd.ctx.GetString(-1), Equals, "FOO,42,function")
+
+	cmd.Require = []string{"os"}
+	c.Assert(cmd.pushPackages(), ErrorMatches, `.*"os"`)
+}
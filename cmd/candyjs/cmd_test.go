@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type CmdSuite struct{}
+
+var _ = Suite(&CmdSuite{})
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// tsReserved are the words that cannot be used as parameter names or as the
+// name of a declaration.
+var tsReserved = map[string]bool{
+	"arguments": true, "catch": true, "class": true, "const": true,
+	"debugger": true, "delete": true, "do": true, "enum": true, "eval": true,
+	"export": true, "extends": true, "false": true, "finally": true,
+	"function": true, "in": true, "instanceof": true, "let": true, "new": true,
+	"null": true, "super": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "void": true, "while": true, "with": true,
+	"yield": true, "await": true, "implements": true, "private": true,
+	"protected": true, "public": true, "static": true,
+}
+
+// dtsWriter writes the TypeScript declaration of the objects of a package,
+// following the conversions done by candyjs when the values are pushed:
+//  - Structs and pointers to structs are proxies, declared as classes with
+//    the fields and methods in lowerCamel case
+//  - Functions returning several values return an array, without the errors
+//  - Functions with a trailing error throw an exception instead of return it
+//  - Slices and maps are copied as plain arrays and objects
+//  - Byte slices are buffers, declared as Uint8Array
+type dtsWriter struct {
+	// names contains the JavaScript names of the bound types
+	names map[*types.TypeName]string
+	b     strings.Builder
+}
+
+func newDtsWriter(objs *packageObjects) *dtsWriter {
+	w := &dtsWriter{names: make(map[*types.TypeName]string, 0)}
+	for _, o := range objs.Objects {
+		if tn, ok := o.obj.(*types.TypeName); ok {
+			w.names[tn] = o.JSName
+		}
+	}
+
+	return w
+}
+
+func (w *dtsWriter) write(fullPkgName string, objs *packageObjects) string {
+	w.printf("// Code generated by candyjs import; DO NOT EDIT.\n\n")
+	w.printf("declare namespace CandyJS {\n")
+	w.printf("\tfunction require(name: %q): typeof import(%q);\n", fullPkgName, fullPkgName)
+	w.printf("}\n\n")
+	w.printf("declare module %q {\n", fullPkgName)
+
+	var block bool
+	for i, o := range objs.Objects {
+		isBlock := o.Kind == kindType || o.Kind == kindInterface
+		if i != 0 && (block || isBlock) {
+			w.printf("\n")
+		}
+
+		block = isBlock
+		w.object(o)
+	}
+
+	w.printf("}\n")
+	return w.b.String()
+}
+
+// object writes the declaration of the object, the names that are reserved
+// words are declared with a trailing underscore and exported with its name.
+func (w *dtsWriter) object(o *object) {
+	name, export := o.JSName, "export "
+	if tsReserved[name] {
+		name, export = name+"_", ""
+	}
+
+	switch o.Kind {
+	case kindFunc:
+		sigs := []*types.Signature{o.signature()}
+		if len(o.Instances) > 1 {
+			sigs = nil
+			for _, inst := range o.Instances {
+				sigs = append(sigs, inst.sig)
+			}
+		}
+
+		for _, sig := range sigs {
+			w.printf("\t%sfunction %s%s;\n", export, name, w.signature(sig, ": "))
+		}
+	case kindType:
+		w.class(o, export, name)
+	case kindInterface:
+		w.iface(o, export, name)
+	case kindVar:
+		keyword := "let"
+		if o.ReadOnly {
+			keyword = "const"
+		}
+
+		w.printf("\t%s%s %s: %s;\n", export, keyword, name, w.typ(o.obj.Type(), false))
+	case kindConst:
+		w.printf("\t%sconst %s: %s;\n", export, name, w.typ(o.obj.Type(), false))
+	}
+
+	if export == "" {
+		w.printf("\texport { %s as %s };\n", name, o.JSName)
+	}
+}
+
+func (w *dtsWriter) class(o *object, export, name string) {
+	t := o.obj.Type()
+	value := w.typ(t.Underlying(), false)
+	if _, ok := t.Underlying().(*types.Struct); ok {
+		value = "Partial<" + name + ">"
+	}
+
+	w.printf("\t%sclass %s {\n", export, name)
+	w.printf("\t\tconstructor(value?: %s);\n", value)
+
+	for _, c := range o.Consts {
+		w.printf("\t\tstatic readonly %s: %s;\n", c.Name, w.typ(c.obj.Type().Underlying(), false))
+	}
+
+	for _, f := range o.Statics {
+		sig := f.obj.Type().(*types.Signature)
+		w.printf("\t\tstatic %s%s;\n", f.JSName, w.signature(sig, ": "))
+	}
+
+	if s, ok := t.Underlying().(*types.Struct); ok {
+		for _, f := range structFields(s) {
+			w.printf("\t\t%s: %s;\n", nameToJavaScript(f.Name()), w.typ(f.Type(), false))
+		}
+	}
+
+	w.methods(types.NewMethodSet(types.NewPointer(t)))
+	w.printf("\t}\n")
+}
+
+// iface writes the interface and the function asserting it, both declarations
+// share the same name.
+func (w *dtsWriter) iface(o *object, export, name string) {
+	w.printf("\t%sinterface %s {\n", export, name)
+	w.methods(types.NewMethodSet(o.obj.Type()))
+	w.printf("\t}\n\n")
+	w.printf("\t%sfunction %s(value: any): %s;\n", export, name, name)
+}
+
+func (w *dtsWriter) methods(ms *types.MethodSet) {
+	for i := 0; i < ms.Len(); i++ {
+		m := ms.At(i).Obj()
+		if !m.Exported() {
+			continue
+		}
+
+		sig := m.Type().(*types.Signature)
+		w.printf("\t\t%s%s;\n", nameToJavaScript(m.Name()), w.signature(sig, ": "))
+	}
+}
+
+// structFields returns the exported fields of a struct, including the ones
+// promoted from embedded structs, shadowed fields are omitted.
+func structFields(s *types.Struct) []*types.Var {
+	var fields []*types.Var
+	seen := make(map[string]bool, 0)
+
+	current := []*types.Struct{s}
+	for len(current) != 0 {
+		var next []*types.Struct
+		var level []*types.Var
+		for _, s := range current {
+			for i := 0; i < s.NumFields(); i++ {
+				f := s.Field(i)
+				if f.Anonymous() {
+					if e, ok := deref(f.Type()).Underlying().(*types.Struct); ok {
+						next = append(next, e)
+					}
+				}
+
+				if f.Exported() && !seen[f.Name()] {
+					level = append(level, f)
+				}
+			}
+		}
+
+		for _, f := range level {
+			seen[f.Name()] = true
+		}
+
+		fields = append(fields, level...)
+		current = next
+	}
+
+	return fields
+}
+
+func (w *dtsWriter) signature(sig *types.Signature, sep string) string {
+	var params []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		p := sig.Params().At(i)
+		name := p.Name()
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+
+		if tsReserved[name] {
+			name += "_"
+		}
+
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			elem := p.Type().(*types.Slice).Elem()
+			params = append(params, "..."+name+": "+w.array(w.typ(elem, false)))
+			continue
+		}
+
+		params = append(params, name+": "+w.typ(p.Type(), false))
+	}
+
+	return "(" + strings.Join(params, ", ") + ")" + sep + w.results(sig.Results())
+}
+
+// results follows the conventions of PushGoFunction: the trailing error is
+// thrown, a single value is returned as is and several as an array.
+func (w *dtsWriter) results(r *types.Tuple) string {
+	var results []string
+	for i := 0; i < r.Len(); i++ {
+		t := r.At(i).Type()
+		if i == r.Len()-1 && isError(t) {
+			continue
+		}
+
+		results = append(results, w.typ(t, false))
+	}
+
+	switch len(results) {
+	case 0:
+		return "void"
+	case 1:
+		return results[0]
+	default:
+		return "[" + strings.Join(results, ", ") + "]"
+	}
+}
+
+// typ returns the TypeScript type of the given one, json should be true for
+// the values copied using encoding/json, where the structs are plain objects.
+func (w *dtsWriter) typ(t types.Type, json bool) string {
+	if name := w.named(t, json); name != "" {
+		return name
+	}
+
+	if isError(t) && !json {
+		return "{ error(): string }"
+	}
+
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return basicType(t)
+	case *types.Pointer:
+		return w.typ(t.Elem(), json)
+	case *types.Slice:
+		// the bytes are pushed as buffers, unless are copied as JSON
+		if b, ok := t.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			if json {
+				return "string"
+			}
+
+			return "Uint8Array"
+		}
+
+		return w.array(w.typ(t.Elem(), true))
+	case *types.Array:
+		return w.array(w.typ(t.Elem(), true))
+	case *types.Map:
+		return "{ [key: string]: " + w.typ(t.Elem(), true) + " }"
+	case *types.Signature:
+		return w.signature(t, " => ")
+	}
+
+	return "any"
+}
+
+// named returns the class or interface name of a bound type, an empty string
+// is returned if the type should be declared by its underlying type.
+func (w *dtsWriter) named(t types.Type, json bool) string {
+	n, ok := types.Unalias(deref(t)).(*types.Named)
+	if !ok || json {
+		return ""
+	}
+
+	name, ok := w.names[n.Obj()]
+	if !ok {
+		return ""
+	}
+
+	switch n.Underlying().(type) {
+	case *types.Struct:
+		return name
+	case *types.Interface:
+		if _, ok := t.(*types.Pointer); !ok {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func (w *dtsWriter) array(elem string) string {
+	if strings.ContainsAny(elem, " (") {
+		return "(" + elem + ")[]"
+	}
+
+	return elem + "[]"
+}
+
+func (w *dtsWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&w.b, format, args...)
+}
+
+func basicType(b *types.Basic) string {
+	switch {
+	case b.Info()&types.IsBoolean != 0:
+		return "boolean"
+	case b.Info()&types.IsNumeric != 0 && b.Info()&types.IsComplex == 0:
+		return "number"
+	case b.Info()&types.IsString != 0:
+		return "string"
+	case b.Kind() == types.UntypedNil:
+		return "null"
+	}
+
+	return "any"
+}
+
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+
+	return t
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
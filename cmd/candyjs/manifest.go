@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// manifest describes the objects of a package to bind, it is read from a YAML
+// or JSON file, based on its extension. Example:
+//
+//   include: ["Get*", "Set*"]
+//   exclude: ["Setenv"]
+//   symbols:
+//     - name: Getenv
+//       rename: env
+//     - name: Args
+//       readonly: true
+//
+// When symbols are given only the listed ones are bound, besides the include
+// and exclude globs.
+type manifest struct {
+	Include []string          `yaml:"include" json:"include"`
+	Exclude []string          `yaml:"exclude" json:"exclude"`
+	Symbols []*manifestSymbol `yaml:"symbols" json:"symbols"`
+}
+
+type manifestSymbol struct {
+	// Name is the Go identifier, or a glob matching several of them
+	Name string `yaml:"name" json:"name"`
+	// Rename is the name of the property in JavaScript
+	Rename string `yaml:"rename" json:"rename"`
+	// ReadOnly properties cannot be replaced from JavaScript and the variables
+	// are pushed as a copy, so its value cannot be changed either
+	ReadOnly bool `yaml:"readonly" json:"readonly"`
+}
+
+func readManifest(filename string) (*manifest, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	if filepath.Ext(filename) == ".json" {
+		err = json.Unmarshal(content, m)
+	} else {
+		err = yaml.UnmarshalStrict(content, m)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %q: %s", filename, err)
+	}
+
+	return m, nil
+}
+
+// validate checks the globs and the symbols, returning an error on malformed
+// patterns or duplicated renames.
+func (m *manifest) validate() error {
+	var patterns []string
+	patterns = append(patterns, m.Include...)
+	patterns = append(patterns, m.Exclude...)
+
+	renames := make(map[string]bool, 0)
+	for _, s := range m.Symbols {
+		if s.Name == "" {
+			return errors.New("manifest symbol without name")
+		}
+
+		if s.Rename != "" && isGlob(s.Name) {
+			return fmt.Errorf("cannot rename the glob %q", s.Name)
+		}
+
+		if renames[s.Rename] {
+			return fmt.Errorf("duplicated rename %q", s.Rename)
+		}
+
+		if s.Rename != "" {
+			renames[s.Rename] = true
+		}
+
+		patterns = append(patterns, s.Name)
+	}
+
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %s", p, err)
+		}
+	}
+
+	return nil
+}
+
+// filter removes the objects not matching the manifest, setting the
+// JavaScript name and read-only flag of the remaining ones. The non-glob
+// symbols not found on the package are returned as error.
+func (m *manifest) filter(objs *packageObjects) error {
+	found := make(map[string]bool, 0)
+
+	var r []*object
+	for _, o := range objs.Objects {
+		if !m.wants(o.Name) {
+			continue
+		}
+
+		if s := m.symbol(o.Name); s != nil {
+			o.ReadOnly = s.ReadOnly
+			if s.Rename != "" {
+				o.JSName = s.Rename
+			}
+		}
+
+		found[o.Name] = true
+		r = append(r, o)
+	}
+
+	var skipped []*skipped
+	for _, s := range objs.Skipped {
+		if m.wants(s.Name) {
+			found[s.Name] = true
+			skipped = append(skipped, s)
+		}
+	}
+
+	for _, s := range m.Symbols {
+		if !found[s.Name] && !isGlob(s.Name) {
+			return fmt.Errorf("symbol %q not found", s.Name)
+		}
+	}
+
+	objs.Objects, objs.Skipped = r, skipped
+	return nil
+}
+
+// wants returns true if the given name is matched by the include globs, or
+// there are none, is not matched by the exclude ones and is listed on the
+// symbols, if any.
+func (m *manifest) wants(name string) bool {
+	if len(m.Include) != 0 && !matchAny(m.Include, name) {
+		return false
+	}
+
+	if matchAny(m.Exclude, name) {
+		return false
+	}
+
+	return len(m.Symbols) == 0 || m.symbol(name) != nil
+}
+
+// symbol returns the symbol matching the given name, the exact matches take
+// precedence over the globs.
+func (m *manifest) symbol(name string) *manifestSymbol {
+	var glob *manifestSymbol
+	for _, s := range m.Symbols {
+		if s.Name == name {
+			return s
+		}
+
+		if ok, _ := path.Match(s.Name, name); ok && glob == nil {
+			glob = s
+		}
+	}
+
+	return glob
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isGlob(pattern string) bool {
+	for _, c := range pattern {
+		switch c {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+
+	return false
+}
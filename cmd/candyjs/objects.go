@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"sort"
+	"strings"
+)
+
+type objectKind string
+
+const (
+	kindFunc      objectKind = "func"
+	kindType      objectKind = "type"
+	kindInterface objectKind = "interface"
+	kindVar       objectKind = "var"
+	kindConst     objectKind = "const"
+)
+
+// object is an exported identifier of the imported package with the data
+// required to render its binding.
+type object struct {
+	Name string
+	// JSName is the name of the property at the package object
+	JSName string
+	Kind   objectKind
+	// Ref is true for the vars pushed as a proxy to the variable itself, this
+	// is, the structs and the types with methods.
+	Ref bool
+	// Static is the code of the candyjs.StaticFunction of the functions
+	Static string
+	// ReadOnly objects are defined as non-writable properties
+	ReadOnly bool
+	// Consts contains the constants of a named type
+	Consts []*object
+	// Statics contains the New functions returning a named type
+	Statics []*object
+	// Instances contains the instantiations of a generic function
+	Instances []*instance
+
+	obj types.Object
+}
+
+// Expr returns the Go expression used to push the object, the integer
+// constants out of the range of an int32 are converted to float64, since the
+// int and uint values are pushed as 32 bits integers.
+func (o *object) Expr(pkg string) string {
+	expr := pkg + "." + o.Name
+
+	c, ok := o.obj.(*types.Const)
+	if !ok || c.Val().Kind() != constant.Int {
+		return expr
+	}
+
+	if v, exact := constant.Int64Val(c.Val()); exact && v >= math.MinInt32 && v <= math.MaxInt32 {
+		return expr
+	}
+
+	return "float64(" + expr + ")"
+}
+
+// Rounded returns true for the integer constants out of the safe integer range
+// of JavaScript, being rounded when are pushed.
+func (o *object) Rounded() bool {
+	c, ok := o.obj.(*types.Const)
+	if !ok || c.Val().Kind() != constant.Int {
+		return false
+	}
+
+	v, exact := constant.Int64Val(c.Val())
+	return !exact || v > maxSafeInteger || v < -maxSafeInteger
+}
+
+// maxSafeInteger is the Number.MAX_SAFE_INTEGER of JavaScript
+const maxSafeInteger = 1<<53 - 1
+
+// instance is an explicit instantiation of a generic function.
+type instance struct {
+	// Expr is the Go expression of the function, like `slices.Index[[]int]`
+	Expr string
+	sig  *types.Signature
+}
+
+// Func returns the Go expression of a function, or the instantiation of the
+// generic functions with a single one.
+func (o *object) Func(pkg string) string {
+	if len(o.Instances) == 1 {
+		return o.Instances[0].Expr
+	}
+
+	return pkg + "." + o.Name
+}
+
+// signature returns the signature of the function, or the instantiation of the
+// generic functions with a single one.
+func (o *object) signature() *types.Signature {
+	if len(o.Instances) == 1 {
+		return o.Instances[0].sig
+	}
+
+	return o.obj.Type().(*types.Signature)
+}
+
+// skipped is an exported identifier that cannot be bound.
+type skipped struct {
+	Name   string
+	Reason string
+}
+
+// packageObjects contains the bindable objects of a package, sorted by name,
+// and the ones skipped.
+type packageObjects struct {
+	Objects []*object
+	Skipped []*skipped
+}
+
+func getPackageObjects(pkg *types.Package) *packageObjects {
+	r := &packageObjects{}
+	types := make(map[types.Type]*object, 0)
+
+	scope := pkg.Scope()
+	names := scope.Names()
+	sort.Strings(names)
+
+	for _, name := range names {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+
+		o, reason := newObject(obj)
+		if o == nil {
+			r.Skipped = append(r.Skipped, &skipped{Name: name, Reason: reason})
+			continue
+		}
+
+		if o.Kind == kindType {
+			types[obj.Type()] = o
+		}
+
+		r.Objects = append(r.Objects, o)
+	}
+
+	for _, o := range r.Objects {
+		if o.Kind != kindConst {
+			continue
+		}
+
+		if t, ok := types[o.obj.Type()]; ok {
+			t.Consts = append(t.Consts, &object{Name: o.Name, Kind: kindConst, obj: o.obj})
+		}
+	}
+
+	return r
+}
+
+// instantiate adds the instantiations of the generic functions given as
+// expressions like `Contains[[]string, string]`, the type arguments are
+// evaluated in the scope of the package.
+func (objs *packageObjects) instantiate(pkg *types.Package, pkgName string, exprs []string) error {
+	generics := make(map[string]*object, 0)
+	for _, expr := range exprs {
+		name, inst, err := newInstance(pkg, pkgName, expr)
+		if err != nil {
+			return fmt.Errorf("invalid instantiation %q: %s", expr, err)
+		}
+
+		o, ok := generics[name]
+		if !ok {
+			obj := pkg.Scope().Lookup(name)
+			o = &object{Name: name, JSName: nameToJavaScript(name), Kind: kindFunc, obj: obj}
+			generics[name] = o
+			objs.Objects = append(objs.Objects, o)
+		}
+
+		o.Instances = append(o.Instances, inst)
+	}
+
+	var skipped []*skipped
+	for _, s := range objs.Skipped {
+		if _, ok := generics[s.Name]; !ok {
+			skipped = append(skipped, s)
+		}
+	}
+
+	objs.Skipped = skipped
+	sort.Slice(objs.Objects, func(i, j int) bool {
+		return objs.Objects[i].Name < objs.Objects[j].Name
+	})
+
+	return nil
+}
+
+func newInstance(pkg *types.Package, pkgName string, expr string) (string, *instance, error) {
+	x, err := parser.ParseExpr(expr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var fn ast.Expr
+	var indices []ast.Expr
+	switch x := x.(type) {
+	case *ast.IndexExpr:
+		fn, indices = x.X, []ast.Expr{x.Index}
+	case *ast.IndexListExpr:
+		fn, indices = x.X, x.Indices
+	default:
+		return "", nil, errors.New("expected Func[T1, T2...]")
+	}
+
+	ident, ok := fn.(*ast.Ident)
+	if !ok {
+		return "", nil, errors.New("expected the name of a function of the package")
+	}
+
+	f, ok := pkg.Scope().Lookup(ident.Name).(*types.Func)
+	if !ok || !f.Exported() || f.Type().(*types.Signature).TypeParams().Len() == 0 {
+		return "", nil, fmt.Errorf("%s is not an exported generic function", ident.Name)
+	}
+
+	var foreign *types.Package
+	var args []string
+	qualifier := func(p *types.Package) string {
+		if p != pkg {
+			foreign = p
+		}
+
+		return pkgName
+	}
+
+	for _, index := range indices {
+		tv, err := types.Eval(token.NewFileSet(), pkg, token.NoPos, types.ExprString(index))
+		if err != nil {
+			return "", nil, err
+		}
+
+		if !tv.IsType() {
+			return "", nil, fmt.Errorf("%s is not a type", types.ExprString(index))
+		}
+
+		args = append(args, types.TypeString(tv.Type, qualifier))
+	}
+
+	if foreign != nil {
+		return "", nil, fmt.Errorf("types of package %q cannot be used", foreign.Path())
+	}
+
+	// the missing type arguments are inferred, like the Go compiler does
+	tv, err := types.Eval(token.NewFileSet(), pkg, token.NoPos, types.ExprString(x))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return f.Name(), &instance{
+		Expr: fmt.Sprintf("%s.%s[%s]", pkgName, f.Name(), strings.Join(args, ", ")),
+		sig:  tv.Type.(*types.Signature),
+	}, nil
+}
+
+// attachStatics adds the functions starting by New, returning a value or a
+// pointer of a type of the package, as statics of the type.
+func (objs *packageObjects) attachStatics() {
+	byType := make(map[*types.TypeName]*object, 0)
+	for _, o := range objs.Objects {
+		if tn, ok := o.obj.(*types.TypeName); ok && o.Kind == kindType {
+			byType[tn] = o
+		}
+	}
+
+	for _, o := range objs.Objects {
+		if o.Kind != kindFunc || len(o.Instances) != 0 || !strings.HasPrefix(o.Name, "New") {
+			continue
+		}
+
+		if t, ok := byType[constructedType(o.obj.Type().(*types.Signature))]; ok {
+			t.Statics = append(t.Statics, o)
+		}
+	}
+}
+
+// constructedType returns the type name of the single value returned by the
+// function, besides an optional error.
+func constructedType(sig *types.Signature) *types.TypeName {
+	r := sig.Results()
+	n := r.Len()
+	if n == 2 && isError(r.At(1).Type()) {
+		n = 1
+	}
+
+	if n != 1 {
+		return nil
+	}
+
+	t := r.At(0).Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return nil
+	}
+
+	return named.Obj()
+}
+
+func newObject(obj types.Object) (*object, string) {
+	o := &object{Name: obj.Name(), JSName: obj.Name(), obj: obj}
+
+	switch obj := obj.(type) {
+	case *types.Func:
+		if obj.Type().(*types.Signature).TypeParams().Len() != 0 {
+			return nil, "generic function, see --instantiate"
+		}
+
+		o.Kind = kindFunc
+		o.JSName = nameToJavaScript(o.Name)
+	case *types.Const:
+		if isComplex(obj.Type()) {
+			return nil, "complex numbers are not supported"
+		}
+
+		o.Kind = kindConst
+	case *types.Var:
+		if isComplex(obj.Type()) {
+			return nil, "complex numbers are not supported"
+		}
+
+		o.Kind = kindVar
+		o.Ref = isReferenceable(obj.Type())
+	case *types.TypeName:
+		return newTypeObject(o, obj)
+	default:
+		return nil, "unsupported object"
+	}
+
+	return o, ""
+}
+
+func newTypeObject(o *object, obj *types.TypeName) (*object, string) {
+	if alias, ok := obj.Type().(*types.Alias); ok && alias.TypeParams().Len() != 0 {
+		return nil, "generic type alias"
+	}
+
+	t := types.Unalias(obj.Type())
+	if named, ok := t.(*types.Named); ok {
+		if named.TypeParams().Len() != 0 && named.TypeArgs().Len() == 0 {
+			return nil, "generic type"
+		}
+	}
+
+	if iface, ok := t.Underlying().(*types.Interface); ok {
+		if !iface.IsMethodSet() {
+			return nil, "constraint interface"
+		}
+
+		o.Kind = kindInterface
+		return o, ""
+	}
+
+	o.Kind = kindType
+	return o, ""
+}
+
+// isReferenceable returns true when the variable should be proxied by
+// reference, keeping the methods with pointer receiver and any change made
+// from JavaScript.
+func isReferenceable(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Struct:
+		return true
+	case *types.Pointer, *types.Interface:
+		return false
+	}
+
+	return types.NewMethodSet(types.NewPointer(t)).Len() != 0
+}
+
+func isComplex(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsComplex != 0
+}
@@ -0,0 +1,101 @@
+// Code generated by candyjs import; DO NOT EDIT.
+
+package pushers
+
+import (
+	"fmt"
+
+	"github.com/mcuadros/go-candyjs"
+)
+
+func init() {
+	candyjs.RegisterPackagePusher("fmt", func(ctx *candyjs.Context) {
+		ctx.PushObject()
+		ctx.PushGoFunction(fmt.Append)
+		ctx.PutPropString(-2, "append")
+
+		ctx.PushGoFunction(fmt.Appendf)
+		ctx.PutPropString(-2, "appendf")
+
+		ctx.PushGoFunction(fmt.Appendln)
+		ctx.PutPropString(-2, "appendln")
+
+		ctx.PushGoFunction(fmt.Errorf)
+		ctx.PutPropString(-2, "errorf")
+
+		ctx.PushGoFunction(fmt.FormatString)
+		ctx.PutPropString(-2, "formatString")
+
+		ctx.PushInterfaceType((*fmt.Formatter)(nil))
+		ctx.PutPropString(-2, "Formatter")
+
+		ctx.PushGoFunction(fmt.Fprint)
+		ctx.PutPropString(-2, "fprint")
+
+		ctx.PushGoFunction(fmt.Fprintf)
+		ctx.PutPropString(-2, "fprintf")
+
+		ctx.PushGoFunction(fmt.Fprintln)
+		ctx.PutPropString(-2, "fprintln")
+
+		ctx.PushGoFunction(fmt.Fscan)
+		ctx.PutPropString(-2, "fscan")
+
+		ctx.PushGoFunction(fmt.Fscanf)
+		ctx.PutPropString(-2, "fscanf")
+
+		ctx.PushGoFunction(fmt.Fscanln)
+		ctx.PutPropString(-2, "fscanln")
+
+		ctx.PushInterfaceType((*fmt.GoStringer)(nil))
+		ctx.PutPropString(-2, "GoStringer")
+
+		ctx.PushGoFunction(fmt.Print)
+		ctx.PutPropString(-2, "print")
+
+		ctx.PushGoFunction(fmt.Printf)
+		ctx.PutPropString(-2, "printf")
+
+		ctx.PushGoFunction(fmt.Println)
+		ctx.PutPropString(-2, "println")
+
+		ctx.PushGoFunction(fmt.Scan)
+		ctx.PutPropString(-2, "scan")
+
+		ctx.PushInterfaceType((*fmt.ScanState)(nil))
+		ctx.PutPropString(-2, "ScanState")
+
+		ctx.PushGoFunction(fmt.Scanf)
+		ctx.PutPropString(-2, "scanf")
+
+		ctx.PushGoFunction(fmt.Scanln)
+		ctx.PutPropString(-2, "scanln")
+
+		ctx.PushInterfaceType((*fmt.Scanner)(nil))
+		ctx.PutPropString(-2, "Scanner")
+
+		ctx.PushGoFunction(fmt.Sprint)
+		ctx.PutPropString(-2, "sprint")
+
+		ctx.PushGoFunction(fmt.Sprintf)
+		ctx.PutPropString(-2, "sprintf")
+
+		ctx.PushGoFunction(fmt.Sprintln)
+		ctx.PutPropString(-2, "sprintln")
+
+		ctx.PushGoFunction(fmt.Sscan)
+		ctx.PutPropString(-2, "sscan")
+
+		ctx.PushGoFunction(fmt.Sscanf)
+		ctx.PutPropString(-2, "sscanf")
+
+		ctx.PushGoFunction(fmt.Sscanln)
+		ctx.PutPropString(-2, "sscanln")
+
+		ctx.PushInterfaceType((*fmt.State)(nil))
+		ctx.PutPropString(-2, "State")
+
+		ctx.PushInterfaceType((*fmt.Stringer)(nil))
+		ctx.PutPropString(-2, "Stringer")
+	})
+}
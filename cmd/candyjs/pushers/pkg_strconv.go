@@ -0,0 +1,128 @@
+// Code generated by candyjs import; DO NOT EDIT.
+
+package pushers
+
+import (
+	"strconv"
+
+	"github.com/mcuadros/go-candyjs"
+)
+
+func init() {
+	candyjs.RegisterPackagePusher("strconv", func(ctx *candyjs.Context) {
+		ctx.PushObject()
+		ctx.PushGoFunction(strconv.AppendBool)
+		ctx.PutPropString(-2, "appendBool")
+
+		ctx.PushGoFunction(strconv.AppendFloat)
+		ctx.PutPropString(-2, "appendFloat")
+
+		ctx.PushGoFunction(strconv.AppendInt)
+		ctx.PutPropString(-2, "appendInt")
+
+		ctx.PushGoFunction(strconv.AppendQuote)
+		ctx.PutPropString(-2, "appendQuote")
+
+		ctx.PushGoFunction(strconv.AppendQuoteRune)
+		ctx.PutPropString(-2, "appendQuoteRune")
+
+		ctx.PushGoFunction(strconv.AppendQuoteRuneToASCII)
+		ctx.PutPropString(-2, "appendQuoteRuneToASCII")
+
+		ctx.PushGoFunction(strconv.AppendQuoteRuneToGraphic)
+		ctx.PutPropString(-2, "appendQuoteRuneToGraphic")
+
+		ctx.PushGoFunction(strconv.AppendQuoteToASCII)
+		ctx.PutPropString(-2, "appendQuoteToASCII")
+
+		ctx.PushGoFunction(strconv.AppendQuoteToGraphic)
+		ctx.PutPropString(-2, "appendQuoteToGraphic")
+
+		ctx.PushGoFunction(strconv.AppendUint)
+		ctx.PutPropString(-2, "appendUint")
+
+		ctx.PushGoFunction(strconv.Atoi)
+		ctx.PutPropString(-2, "atoi")
+
+		ctx.PushGoFunction(strconv.CanBackquote)
+		ctx.PutPropString(-2, "canBackquote")
+
+		ctx.PushInterface(strconv.ErrRange)
+		ctx.PutPropString(-2, "ErrRange")
+
+		ctx.PushInterface(strconv.ErrSyntax)
+		ctx.PutPropString(-2, "ErrSyntax")
+
+		ctx.PushGoFunction(strconv.FormatBool)
+		ctx.PutPropString(-2, "formatBool")
+
+		ctx.PushGoFunction(strconv.FormatComplex)
+		ctx.PutPropString(-2, "formatComplex")
+
+		ctx.PushGoFunction(strconv.FormatFloat)
+		ctx.PutPropString(-2, "formatFloat")
+
+		ctx.PushGoFunction(strconv.FormatInt)
+		ctx.PutPropString(-2, "formatInt")
+
+		ctx.PushGoFunction(strconv.FormatUint)
+		ctx.PutPropString(-2, "formatUint")
+
+		ctx.PushInterface(strconv.IntSize)
+		ctx.PutPropString(-2, "IntSize")
+
+		ctx.PushGoFunction(strconv.IsGraphic)
+		ctx.PutPropString(-2, "isGraphic")
+
+		ctx.PushGoFunction(strconv.IsPrint)
+		ctx.PutPropString(-2, "isPrint")
+
+		ctx.PushGoFunction(strconv.Itoa)
+		ctx.PutPropString(-2, "itoa")
+
+		ctx.PushType(*new(strconv.NumError))
+		ctx.PutPropString(-2, "NumError")
+
+		ctx.PushGoFunction(strconv.ParseBool)
+		ctx.PutPropString(-2, "parseBool")
+
+		ctx.PushGoFunction(strconv.ParseComplex)
+		ctx.PutPropString(-2, "parseComplex")
+
+		ctx.PushGoFunction(strconv.ParseFloat)
+		ctx.PutPropString(-2, "parseFloat")
+
+		ctx.PushGoFunction(strconv.ParseInt)
+		ctx.PutPropString(-2, "parseInt")
+
+		ctx.PushGoFunction(strconv.ParseUint)
+		ctx.PutPropString(-2, "parseUint")
+
+		ctx.PushGoFunction(strconv.Quote)
+		ctx.PutPropString(-2, "quote")
+
+		ctx.PushGoFunction(strconv.QuoteRune)
+		ctx.PutPropString(-2, "quoteRune")
+
+		ctx.PushGoFunction(strconv.QuoteRuneToASCII)
+		ctx.PutPropString(-2, "quoteRuneToASCII")
+
+		ctx.PushGoFunction(strconv.QuoteRuneToGraphic)
+		ctx.PutPropString(-2, "quoteRuneToGraphic")
+
+		ctx.PushGoFunction(strconv.QuoteToASCII)
+		ctx.PutPropString(-2, "quoteToASCII")
+
+		ctx.PushGoFunction(strconv.QuoteToGraphic)
+		ctx.PutPropString(-2, "quoteToGraphic")
+
+		ctx.PushGoFunction(strconv.QuotedPrefix)
+		ctx.PutPropString(-2, "quotedPrefix")
+
+		ctx.PushGoFunction(strconv.Unquote)
+		ctx.PutPropString(-2, "unquote")
+
+		ctx.PushGoFunction(strconv.UnquoteChar)
+		ctx.PutPropString(-2, "unquoteChar")
+	})
+}
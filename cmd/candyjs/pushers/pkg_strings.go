@@ -0,0 +1,198 @@
+// Code generated by candyjs import; DO NOT EDIT.
+
+package pushers
+
+import (
+	"strings"
+
+	"github.com/mcuadros/go-candyjs"
+)
+
+func init() {
+	candyjs.RegisterPackagePusher("strings", func(ctx *candyjs.Context) {
+		ctx.PushObject()
+		ctx.PushType(*new(strings.Builder))
+		ctx.PutPropString(-2, "Builder")
+
+		ctx.PushGoFunction(strings.Clone)
+		ctx.PutPropString(-2, "clone")
+
+		ctx.PushGoFunction(strings.Compare)
+		ctx.PutPropString(-2, "compare")
+
+		ctx.PushGoFunction(strings.Contains)
+		ctx.PutPropString(-2, "contains")
+
+		ctx.PushGoFunction(strings.ContainsAny)
+		ctx.PutPropString(-2, "containsAny")
+
+		ctx.PushGoFunction(strings.ContainsFunc)
+		ctx.PutPropString(-2, "containsFunc")
+
+		ctx.PushGoFunction(strings.ContainsRune)
+		ctx.PutPropString(-2, "containsRune")
+
+		ctx.PushGoFunction(strings.Count)
+		ctx.PutPropString(-2, "count")
+
+		ctx.PushGoFunction(strings.Cut)
+		ctx.PutPropString(-2, "cut")
+
+		ctx.PushGoFunction(strings.CutPrefix)
+		ctx.PutPropString(-2, "cutPrefix")
+
+		ctx.PushGoFunction(strings.CutSuffix)
+		ctx.PutPropString(-2, "cutSuffix")
+
+		ctx.PushGoFunction(strings.EqualFold)
+		ctx.PutPropString(-2, "equalFold")
+
+		ctx.PushGoFunction(strings.Fields)
+		ctx.PutPropString(-2, "fields")
+
+		ctx.PushGoFunction(strings.FieldsFunc)
+		ctx.PutPropString(-2, "fieldsFunc")
+
+		ctx.PushGoFunction(strings.FieldsFuncSeq)
+		ctx.PutPropString(-2, "fieldsFuncSeq")
+
+		ctx.PushGoFunction(strings.FieldsSeq)
+		ctx.PutPropString(-2, "fieldsSeq")
+
+		ctx.PushGoFunction(strings.HasPrefix)
+		ctx.PutPropString(-2, "hasPrefix")
+
+		ctx.PushGoFunction(strings.HasSuffix)
+		ctx.PutPropString(-2, "hasSuffix")
+
+		ctx.PushGoFunction(strings.Index)
+		ctx.PutPropString(-2, "index")
+
+		ctx.PushGoFunction(strings.IndexAny)
+		ctx.PutPropString(-2, "indexAny")
+
+		ctx.PushGoFunction(strings.IndexByte)
+		ctx.PutPropString(-2, "indexByte")
+
+		ctx.PushGoFunction(strings.IndexFunc)
+		ctx.PutPropString(-2, "indexFunc")
+
+		ctx.PushGoFunction(strings.IndexRune)
+		ctx.PutPropString(-2, "indexRune")
+
+		ctx.PushGoFunction(strings.Join)
+		ctx.PutPropString(-2, "join")
+
+		ctx.PushGoFunction(strings.LastIndex)
+		ctx.PutPropString(-2, "lastIndex")
+
+		ctx.PushGoFunction(strings.LastIndexAny)
+		ctx.PutPropString(-2, "lastIndexAny")
+
+		ctx.PushGoFunction(strings.LastIndexByte)
+		ctx.PutPropString(-2, "lastIndexByte")
+
+		ctx.PushGoFunction(strings.LastIndexFunc)
+		ctx.PutPropString(-2, "lastIndexFunc")
+
+		ctx.PushGoFunction(strings.Lines)
+		ctx.PutPropString(-2, "lines")
+
+		ctx.PushGoFunction(strings.Map)
+		ctx.PutPropString(-2, "map")
+
+		ctx.PushGoFunction(strings.NewReader)
+		ctx.PutPropString(-2, "newReader")
+
+		ctx.PushGoFunction(strings.NewReplacer)
+		ctx.PutPropString(-2, "newReplacer")
+
+		ctx.PushType(*new(strings.Reader))
+		ctx.PushGoFunction(strings.NewReader)
+		ctx.PutPropString(-2, "newReader")
+		ctx.PutPropString(-2, "Reader")
+
+		ctx.PushGoFunction(strings.Repeat)
+		ctx.PutPropString(-2, "repeat")
+
+		ctx.PushGoFunction(strings.Replace)
+		ctx.PutPropString(-2, "replace")
+
+		ctx.PushGoFunction(strings.ReplaceAll)
+		ctx.PutPropString(-2, "replaceAll")
+
+		ctx.PushType(*new(strings.Replacer))
+		ctx.PushGoFunction(strings.NewReplacer)
+		ctx.PutPropString(-2, "newReplacer")
+		ctx.PutPropString(-2, "Replacer")
+
+		ctx.PushGoFunction(strings.Split)
+		ctx.PutPropString(-2, "split")
+
+		ctx.PushGoFunction(strings.SplitAfter)
+		ctx.PutPropString(-2, "splitAfter")
+
+		ctx.PushGoFunction(strings.SplitAfterN)
+		ctx.PutPropString(-2, "splitAfterN")
+
+		ctx.PushGoFunction(strings.SplitAfterSeq)
+		ctx.PutPropString(-2, "splitAfterSeq")
+
+		ctx.PushGoFunction(strings.SplitN)
+		ctx.PutPropString(-2, "splitN")
+
+		ctx.PushGoFunction(strings.SplitSeq)
+		ctx.PutPropString(-2, "splitSeq")
+
+		ctx.PushGoFunction(strings.Title)
+		ctx.PutPropString(-2, "title")
+
+		ctx.PushGoFunction(strings.ToLower)
+		ctx.PutPropString(-2, "toLower")
+
+		ctx.PushGoFunction(strings.ToLowerSpecial)
+		ctx.PutPropString(-2, "toLowerSpecial")
+
+		ctx.PushGoFunction(strings.ToTitle)
+		ctx.PutPropString(-2, "toTitle")
+
+		ctx.PushGoFunction(strings.ToTitleSpecial)
+		ctx.PutPropString(-2, "toTitleSpecial")
+
+		ctx.PushGoFunction(strings.ToUpper)
+		ctx.PutPropString(-2, "toUpper")
+
+		ctx.PushGoFunction(strings.ToUpperSpecial)
+		ctx.PutPropString(-2, "toUpperSpecial")
+
+		ctx.PushGoFunction(strings.ToValidUTF8)
+		ctx.PutPropString(-2, "toValidUTF8")
+
+		ctx.PushGoFunction(strings.Trim)
+		ctx.PutPropString(-2, "trim")
+
+		ctx.PushGoFunction(strings.TrimFunc)
+		ctx.PutPropString(-2, "trimFunc")
+
+		ctx.PushGoFunction(strings.TrimLeft)
+		ctx.PutPropString(-2, "trimLeft")
+
+		ctx.PushGoFunction(strings.TrimLeftFunc)
+		ctx.PutPropString(-2, "trimLeftFunc")
+
+		ctx.PushGoFunction(strings.TrimPrefix)
+		ctx.PutPropString(-2, "trimPrefix")
+
+		ctx.PushGoFunction(strings.TrimRight)
+		ctx.PutPropString(-2, "trimRight")
+
+		ctx.PushGoFunction(strings.TrimRightFunc)
+		ctx.PutPropString(-2, "trimRightFunc")
+
+		ctx.PushGoFunction(strings.TrimSpace)
+		ctx.PutPropString(-2, "trimSpace")
+
+		ctx.PushGoFunction(strings.TrimSuffix)
+		ctx.PutPropString(-2, "trimSuffix")
+	})
+}
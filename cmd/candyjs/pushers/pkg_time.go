@@ -0,0 +1,287 @@
+// Code generated by candyjs import; DO NOT EDIT.
+
+package pushers
+
+import (
+	"time"
+
+	"github.com/mcuadros/go-candyjs"
+)
+
+func init() {
+	candyjs.RegisterPackagePusher("time", func(ctx *candyjs.Context) {
+		ctx.PushObject()
+		ctx.PushInterface(time.ANSIC)
+		ctx.PutPropString(-2, "ANSIC")
+
+		ctx.PushGoFunction(time.After)
+		ctx.PutPropString(-2, "after")
+
+		ctx.PushGoFunction(time.AfterFunc)
+		ctx.PutPropString(-2, "afterFunc")
+
+		ctx.PushInterface(time.April)
+		ctx.PutPropString(-2, "April")
+
+		ctx.PushInterface(time.August)
+		ctx.PutPropString(-2, "August")
+
+		ctx.PushGoFunction(time.Date)
+		ctx.PutPropString(-2, "date")
+
+		ctx.PushInterface(time.DateOnly)
+		ctx.PutPropString(-2, "DateOnly")
+
+		ctx.PushInterface(time.DateTime)
+		ctx.PutPropString(-2, "DateTime")
+
+		ctx.PushInterface(time.December)
+		ctx.PutPropString(-2, "December")
+
+		ctx.PushType(*new(time.Duration))
+		ctx.PushInterface(float64(time.Hour))
+		ctx.PutPropString(-2, "Hour")
+		ctx.PushInterface(time.Microsecond)
+		ctx.PutPropString(-2, "Microsecond")
+		ctx.PushInterface(time.Millisecond)
+		ctx.PutPropString(-2, "Millisecond")
+		ctx.PushInterface(float64(time.Minute))
+		ctx.PutPropString(-2, "Minute")
+		ctx.PushInterface(time.Nanosecond)
+		ctx.PutPropString(-2, "Nanosecond")
+		ctx.PushInterface(time.Second)
+		ctx.PutPropString(-2, "Second")
+		ctx.PutPropString(-2, "Duration")
+
+		ctx.PushInterface(time.February)
+		ctx.PutPropString(-2, "February")
+
+		ctx.PushGoFunction(time.FixedZone)
+		ctx.PutPropString(-2, "fixedZone")
+
+		ctx.PushInterface(time.Friday)
+		ctx.PutPropString(-2, "Friday")
+
+		ctx.PushInterface(float64(time.Hour))
+		ctx.PutPropString(-2, "Hour")
+
+		ctx.PushInterface(time.January)
+		ctx.PutPropString(-2, "January")
+
+		ctx.PushInterface(time.July)
+		ctx.PutPropString(-2, "July")
+
+		ctx.PushInterface(time.June)
+		ctx.PutPropString(-2, "June")
+
+		ctx.PushInterface(time.Kitchen)
+		ctx.PutPropString(-2, "Kitchen")
+
+		ctx.PushInterface(time.Layout)
+		ctx.PutPropString(-2, "Layout")
+
+		ctx.PushGoFunction(time.LoadLocation)
+		ctx.PutPropString(-2, "loadLocation")
+
+		ctx.PushGoFunction(time.LoadLocationFromTZData)
+		ctx.PutPropString(-2, "loadLocationFromTZData")
+
+		ctx.PushInterface(time.Local)
+		ctx.PutPropString(-2, "Local")
+
+		ctx.PushType(*new(time.Location))
+		ctx.PutPropString(-2, "Location")
+
+		ctx.PushInterface(time.March)
+		ctx.PutPropString(-2, "March")
+
+		ctx.PushInterface(time.May)
+		ctx.PutPropString(-2, "May")
+
+		ctx.PushInterface(time.Microsecond)
+		ctx.PutPropString(-2, "Microsecond")
+
+		ctx.PushInterface(time.Millisecond)
+		ctx.PutPropString(-2, "Millisecond")
+
+		ctx.PushInterface(float64(time.Minute))
+		ctx.PutPropString(-2, "Minute")
+
+		ctx.PushInterface(time.Monday)
+		ctx.PutPropString(-2, "Monday")
+
+		ctx.PushType(*new(time.Month))
+		ctx.PushInterface(time.April)
+		ctx.PutPropString(-2, "April")
+		ctx.PushInterface(time.August)
+		ctx.PutPropString(-2, "August")
+		ctx.PushInterface(time.December)
+		ctx.PutPropString(-2, "December")
+		ctx.PushInterface(time.February)
+		ctx.PutPropString(-2, "February")
+		ctx.PushInterface(time.January)
+		ctx.PutPropString(-2, "January")
+		ctx.PushInterface(time.July)
+		ctx.PutPropString(-2, "July")
+		ctx.PushInterface(time.June)
+		ctx.PutPropString(-2, "June")
+		ctx.PushInterface(time.March)
+		ctx.PutPropString(-2, "March")
+		ctx.PushInterface(time.May)
+		ctx.PutPropString(-2, "May")
+		ctx.PushInterface(time.November)
+		ctx.PutPropString(-2, "November")
+		ctx.PushInterface(time.October)
+		ctx.PutPropString(-2, "October")
+		ctx.PushInterface(time.September)
+		ctx.PutPropString(-2, "September")
+		ctx.PutPropString(-2, "Month")
+
+		ctx.PushInterface(time.Nanosecond)
+		ctx.PutPropString(-2, "Nanosecond")
+
+		ctx.PushGoFunction(time.NewTicker)
+		ctx.PutPropString(-2, "newTicker")
+
+		ctx.PushGoFunction(time.NewTimer)
+		ctx.PutPropString(-2, "newTimer")
+
+		ctx.PushInterface(time.November)
+		ctx.PutPropString(-2, "November")
+
+		ctx.PushGoFunction(time.Now)
+		ctx.PutPropString(-2, "now")
+
+		ctx.PushInterface(time.October)
+		ctx.PutPropString(-2, "October")
+
+		ctx.PushGoFunction(time.Parse)
+		ctx.PutPropString(-2, "parse")
+
+		ctx.PushGoFunction(time.ParseDuration)
+		ctx.PutPropString(-2, "parseDuration")
+
+		ctx.PushType(*new(time.ParseError))
+		ctx.PutPropString(-2, "ParseError")
+
+		ctx.PushGoFunction(time.ParseInLocation)
+		ctx.PutPropString(-2, "parseInLocation")
+
+		ctx.PushInterface(time.RFC1123)
+		ctx.PutPropString(-2, "RFC1123")
+
+		ctx.PushInterface(time.RFC1123Z)
+		ctx.PutPropString(-2, "RFC1123Z")
+
+		ctx.PushInterface(time.RFC3339)
+		ctx.PutPropString(-2, "RFC3339")
+
+		ctx.PushInterface(time.RFC3339Nano)
+		ctx.PutPropString(-2, "RFC3339Nano")
+
+		ctx.PushInterface(time.RFC822)
+		ctx.PutPropString(-2, "RFC822")
+
+		ctx.PushInterface(time.RFC822Z)
+		ctx.PutPropString(-2, "RFC822Z")
+
+		ctx.PushInterface(time.RFC850)
+		ctx.PutPropString(-2, "RFC850")
+
+		ctx.PushInterface(time.RubyDate)
+		ctx.PutPropString(-2, "RubyDate")
+
+		ctx.PushInterface(time.Saturday)
+		ctx.PutPropString(-2, "Saturday")
+
+		ctx.PushInterface(time.Second)
+		ctx.PutPropString(-2, "Second")
+
+		ctx.PushInterface(time.September)
+		ctx.PutPropString(-2, "September")
+
+		ctx.PushGoFunction(time.Since)
+		ctx.PutPropString(-2, "since")
+
+		ctx.PushGoFunction(time.Sleep)
+		ctx.PutPropString(-2, "sleep")
+
+		ctx.PushInterface(time.Stamp)
+		ctx.PutPropString(-2, "Stamp")
+
+		ctx.PushInterface(time.StampMicro)
+		ctx.PutPropString(-2, "StampMicro")
+
+		ctx.PushInterface(time.StampMilli)
+		ctx.PutPropString(-2, "StampMilli")
+
+		ctx.PushInterface(time.StampNano)
+		ctx.PutPropString(-2, "StampNano")
+
+		ctx.PushInterface(time.Sunday)
+		ctx.PutPropString(-2, "Sunday")
+
+		ctx.PushInterface(time.Thursday)
+		ctx.PutPropString(-2, "Thursday")
+
+		ctx.PushGoFunction(time.Tick)
+		ctx.PutPropString(-2, "tick")
+
+		ctx.PushType(*new(time.Ticker))
+		ctx.PushGoFunction(time.NewTicker)
+		ctx.PutPropString(-2, "newTicker")
+		ctx.PutPropString(-2, "Ticker")
+
+		ctx.PushType(*new(time.Time))
+		ctx.PutPropString(-2, "Time")
+
+		ctx.PushInterface(time.TimeOnly)
+		ctx.PutPropString(-2, "TimeOnly")
+
+		ctx.PushType(*new(time.Timer))
+		ctx.PushGoFunction(time.NewTimer)
+		ctx.PutPropString(-2, "newTimer")
+		ctx.PutPropString(-2, "Timer")
+
+		ctx.PushInterface(time.Tuesday)
+		ctx.PutPropString(-2, "Tuesday")
+
+		ctx.PushInterface(time.UTC)
+		ctx.PutPropString(-2, "UTC")
+
+		ctx.PushGoFunction(time.Unix)
+		ctx.PutPropString(-2, "unix")
+
+		ctx.PushInterface(time.UnixDate)
+		ctx.PutPropString(-2, "UnixDate")
+
+		ctx.PushGoFunction(time.UnixMicro)
+		ctx.PutPropString(-2, "unixMicro")
+
+		ctx.PushGoFunction(time.UnixMilli)
+		ctx.PutPropString(-2, "unixMilli")
+
+		ctx.PushGoFunction(time.Until)
+		ctx.PutPropString(-2, "until")
+
+		ctx.PushInterface(time.Wednesday)
+		ctx.PutPropString(-2, "Wednesday")
+
+		ctx.PushType(*new(time.Weekday))
+		ctx.PushInterface(time.Friday)
+		ctx.PutPropString(-2, "Friday")
+		ctx.PushInterface(time.Monday)
+		ctx.PutPropString(-2, "Monday")
+		ctx.PushInterface(time.Saturday)
+		ctx.PutPropString(-2, "Saturday")
+		ctx.PushInterface(time.Sunday)
+		ctx.PutPropString(-2, "Sunday")
+		ctx.PushInterface(time.Thursday)
+		ctx.PutPropString(-2, "Thursday")
+		ctx.PushInterface(time.Tuesday)
+		ctx.PutPropString(-2, "Tuesday")
+		ctx.PushInterface(time.Wednesday)
+		ctx.PutPropString(-2, "Wednesday")
+		ctx.PutPropString(-2, "Weekday")
+	})
+}
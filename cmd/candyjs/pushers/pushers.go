@@ -0,0 +1,9 @@
+// Package pushers registers the packages available by default to the repl of
+// the candyjs binary. The symbols added after Go 1.25 are excluded, since the
+// module should build with it.
+package pushers
+
+//go:generate candyjs import fmt
+//go:generate candyjs import strconv
+//go:generate candyjs import --exclude CutLast strings
+//go:generate candyjs import time
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// staticWrapper returns the code of a candyjs.StaticFunction calling the
+// given function, without reflection. An empty string is returned when the
+// function cannot be specialised: the arguments should be of a predeclared
+// basic type and the results of a basic type, plus an optional trailing error.
+func staticWrapper(pkg string, o *object) string {
+	sig := o.signature()
+	if sig.Variadic() || sig.TypeParams().Len() != 0 {
+		return ""
+	}
+
+	var args, reads, checks []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		typ := staticArg(sig.Params().At(i).Type())
+		if typ == "" {
+			return ""
+		}
+
+		arg := fmt.Sprintf("a%d", i)
+		reads = append(reads, fmt.Sprintf(
+			"%s, ok%d := candyjs.StaticArg[%s](ctx, %d)", arg, i, typ, i,
+		))
+
+		args = append(args, arg)
+		checks = append(checks, fmt.Sprintf("!ok%d", i))
+	}
+
+	results := sig.Results()
+	returnError := results.Len() != 0 && isError(results.At(results.Len()-1).Type())
+
+	var names, pushes []string
+	for i := 0; i < results.Len(); i++ {
+		name := fmt.Sprintf("r%d", i)
+		if returnError && i == results.Len()-1 {
+			names = append(names, "err")
+			continue
+		}
+
+		push := staticPush(results.At(i).Type(), name)
+		if push == "" {
+			return ""
+		}
+
+		names = append(names, name)
+		pushes = append(pushes, push)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "func(ctx *candyjs.Context) (int, error) {\n")
+	if len(reads) != 0 {
+		fmt.Fprintf(buf, "%s\n", strings.Join(reads, "\n"))
+		fmt.Fprintf(buf, "if %s {\n", strings.Join(checks, " || "))
+		fmt.Fprintf(buf, "return 0, candyjs.ErrStaticArgument\n}\n\n")
+	}
+
+	call := fmt.Sprintf("%s(%s)", o.Func(pkg), strings.Join(args, ", "))
+	if len(names) == 0 {
+		fmt.Fprintf(buf, "%s\n", call)
+	} else {
+		fmt.Fprintf(buf, "%s := %s\n", strings.Join(names, ", "), call)
+	}
+
+	if returnError {
+		fmt.Fprintf(buf, "if err != nil {\nreturn 0, err\n}\n\n")
+	}
+
+	switch len(pushes) {
+	case 0:
+		fmt.Fprintf(buf, "return 0, nil\n")
+	case 1:
+		fmt.Fprintf(buf, "%s\nreturn 1, nil\n", pushes[0])
+	default:
+		fmt.Fprintf(buf, "ctx.PushArray()\n")
+		for i, push := range pushes {
+			fmt.Fprintf(buf, "%s\nctx.PutPropIndex(-2, %d)\n", push, i)
+		}
+
+		fmt.Fprintf(buf, "return 1, nil\n")
+	}
+
+	fmt.Fprintf(buf, "}")
+	return buf.String()
+}
+
+// staticArg returns the type of the argument read by candyjs.StaticArg, only
+// the predeclared types are supported, since the named ones can be given as a
+// proxy, created by its constructor. The arguments missing or of other type
+// make the wrapper return candyjs.ErrStaticArgument, being converted with
+// reflection instead.
+func staticArg(t types.Type) string {
+	b, ok := t.(*types.Basic)
+	if !ok {
+		return ""
+	}
+
+	if b.Kind() == types.Bool || b.Kind() == types.String ||
+		b.Kind() == types.Float64 || isStaticNumber(b) {
+		return b.Name()
+	}
+
+	return ""
+}
+
+// staticPush returns the statement pushing the given value, following the
+// same rules as candyjs.PushInterface, except for the int and uint values that
+// are pushed as numbers, since PushInt and PushUint truncate them to 32 bits.
+func staticPush(t types.Type, name string) string {
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+
+	convert := func(typ string) string {
+		if types.Identical(t, types.Universe.Lookup(typ).Type()) {
+			return name
+		}
+
+		return typ + "(" + name + ")"
+	}
+
+	switch b.Kind() {
+	case types.Bool:
+		return fmt.Sprintf("ctx.PushBoolean(%s)", convert("bool"))
+	case types.String:
+		return fmt.Sprintf("ctx.PushUTF8String(%s)", convert("string"))
+	case types.Int8, types.Int16, types.Int32:
+		return fmt.Sprintf("ctx.PushInt(%s)", convert("int"))
+	case types.Uint8, types.Uint16, types.Uint32:
+		return fmt.Sprintf("ctx.PushUint(%s)", convert("uint"))
+	case types.Int, types.Uint, types.Int64, types.Uint64, types.Float32, types.Float64:
+		return fmt.Sprintf("ctx.PushNumber(%s)", convert("float64"))
+	}
+
+	return ""
+}
+
+func isStaticNumber(b *types.Basic) bool {
+	switch b.Kind() {
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64,
+		types.Float32:
+		return true
+	}
+
+	return false
+}
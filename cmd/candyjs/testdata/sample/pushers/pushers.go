@@ -0,0 +1,2 @@
+// Package pushers is where the golden tests of candyjs import are run.
+package pushers
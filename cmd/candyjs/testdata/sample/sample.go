@@ -0,0 +1,69 @@
+// Package sample is imported by the golden tests of candyjs import.
+package sample
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	Name  = "sample"
+	Small = 42
+	Big   = 1 << 40
+	Huge  = 1 << 62
+)
+
+type Color int
+
+const (
+	Red  Color = 1
+	Blue Color = 2
+)
+
+var Counter int
+
+var ErrEmpty = errors.New("empty")
+
+type Point struct {
+	X, Y int
+}
+
+func NewPoint(x, y int) *Point {
+	return &Point{X: x, Y: y}
+}
+
+func (p *Point) Add(o *Point) *Point {
+	return &Point{X: p.X + o.X, Y: p.Y + o.Y}
+}
+
+type Shape interface {
+	Area() float64
+}
+
+func Sum(a, b int) int {
+	return a + b
+}
+
+func Repeat(s string, n uint8) string {
+	return strings.Repeat(s, int(n))
+}
+
+func Encode(s string) []byte {
+	return []byte(s)
+}
+
+func Decode(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", ErrEmpty
+	}
+
+	return string(b), nil
+}
+
+func Max[T int | float64](a, b T) T {
+	if a > b {
+		return a
+	}
+
+	return b
+}
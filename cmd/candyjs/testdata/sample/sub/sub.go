@@ -0,0 +1,5 @@
+package sub
+
+func Hello(name string) string {
+	return "hello " + name
+}
@@ -0,0 +1,235 @@
+package candyjs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// ConsoleMessage is a message written by a script using the console.
+type ConsoleMessage struct {
+	// Level is Debug for console.debug, Info for console.log and console.info,
+	// Warn for console.warn and Error for console.error
+	Level slog.Level
+	// Message are the arguments formatted like the util.format function of
+	// Node.js does, the values are formatted using Context.Inspect
+	Message string
+	// FileName and LineNumber are the position of the call, if known
+	FileName   string
+	LineNumber int
+}
+
+// Console receives the messages written with the `console` global.
+type Console interface {
+	Log(msg *ConsoleMessage)
+}
+
+type writerConsole struct {
+	stdout, stderr io.Writer
+}
+
+// NewWriterConsole returns a Console writing every message as a line, the
+// warnings and errors are written to stderr and the others to stdout.
+func NewWriterConsole(stdout, stderr io.Writer) Console {
+	return &writerConsole{stdout: stdout, stderr: stderr}
+}
+
+func (c *writerConsole) Log(msg *ConsoleMessage) {
+	w := c.stdout
+	if msg.Level >= slog.LevelWarn {
+		w = c.stderr
+	}
+
+	fmt.Fprintln(w, msg.Message)
+}
+
+type slogConsole struct {
+	h slog.Handler
+}
+
+// NewSlogConsole returns a Console sending the messages to a slog.Handler,
+// the position of the call is added as the `filename` and `line` attributes.
+func NewSlogConsole(h slog.Handler) Console {
+	return &slogConsole{h: h}
+}
+
+func (c *slogConsole) Log(msg *ConsoleMessage) {
+	ctx := context.Background()
+	if !c.h.Enabled(ctx, msg.Level) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), msg.Level, msg.Message, 0)
+	if msg.FileName != "" {
+		r.AddAttrs(
+			slog.String("filename", msg.FileName),
+			slog.Int("line", msg.LineNumber),
+		)
+	}
+
+	c.h.Handle(ctx, r)
+}
+
+var consoleLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"log":   slog.LevelInfo,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// pushGlobalConsole defines the `console` global, sending the messages to the
+// given Console.
+func (ctx *Context) pushGlobalConsole(c Console) {
+	ctx.Context.PevalString(consoleJS)
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		msg := &ConsoleMessage{
+			Level:   consoleLevels[ctx.GetString(0)],
+			Message: ctx.format(3),
+		}
+
+		if ctx.IsString(1) {
+			msg.FileName, msg.LineNumber = ctx.GetString(1), ctx.GetInt(2)
+			if source, line, ok := ctx.position(msg.FileName, msg.LineNumber); ok {
+				msg.FileName, msg.LineNumber = source, line
+			}
+		}
+
+		c.Log(msg)
+		return 0
+	})
+
+	ctx.Call(1)
+	ctx.PushGlobalObject()
+	ctx.Swap(-2, -1)
+	ctx.PutPropString(-2, "console")
+	ctx.Pop()
+}
+
+// pushGlobalPrint defines a global function like `print`, writing its
+// arguments converted to strings, separated by spaces, as a line.
+func (ctx *Context) pushGlobalPrint(name string, w io.Writer) {
+	ctx.PushGlobalObject()
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		parts := make([]string, ctx.GetTop())
+		for i := range parts {
+			parts[i] = ctx.SafeToString(i)
+		}
+
+		io.WriteString(w, strings.Join(parts, " ")+"\n")
+		return 0
+	})
+
+	ctx.PutPropString(-2, name)
+	ctx.Pop()
+}
+
+const consoleJS = `(function (write) {
+	var console = {};
+	['debug', 'log', 'info', 'warn', 'error'].forEach(function (level) {
+		console[level] = function () {
+			var act = Duktape.act(-3) || {};
+			var fn = act['function'] || {};
+			var args = Array.prototype.slice.call(arguments);
+			write.apply(null, [level, fn.fileName, act.lineNumber].concat(args));
+		};
+	});
+
+	return console;
+})`
+
+// format formats the values from the given index to the top of the stack,
+// following the util.format function of Node.js: the first value can contain
+// %s, %d, %i, %f, %j, %o, %O and %% placeholders, the rest of values are
+// appended separated by spaces. The strings are written as they are and the
+// other values using Inspect, except the Go errors, written by its message.
+func (ctx *Context) format(from int) string {
+	top := ctx.GetTop()
+	if from >= top {
+		return ""
+	}
+
+	index := from
+	var parts []string
+	if ctx.IsString(index) {
+		var s string
+		s, index = ctx.formatString(ctx.GetString(index), index+1, top)
+		parts = append(parts, s)
+	}
+
+	for ; index < top; index++ {
+		parts = append(parts, ctx.formatValue(index))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// formatString replaces the placeholders of the format string by the values
+// starting at the given index, returning the index of the first value not
+// used.
+func (ctx *Context) formatString(format string, index, top int) (string, int) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		verb := format[i+1]
+		if verb == '%' {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+
+		if !strings.ContainsRune("sdifjoO", rune(verb)) || index >= top {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		switch verb {
+		case 's':
+			b.WriteString(ctx.formatValue(index))
+		case 'd', 'f':
+			b.WriteString(strconv.FormatFloat(ctx.formatNumber(index), 'f', -1, 64))
+		case 'i':
+			b.WriteString(strconv.FormatInt(int64(ctx.formatNumber(index)), 10))
+		case 'j':
+			ctx.Dup(index)
+			b.WriteString(ctx.JsonEncode(-1))
+			ctx.Pop()
+		case 'o', 'O':
+			b.WriteString(ctx.Inspect(index))
+		}
+
+		index++
+		i++
+	}
+
+	return b.String(), index
+}
+
+func (ctx *Context) formatNumber(index int) float64 {
+	ctx.Dup(index)
+	defer ctx.Pop()
+
+	return ctx.ToNumber(-1)
+}
+
+func (ctx *Context) formatValue(index int) string {
+	if ctx.IsString(index) {
+		return ctx.GetString(index)
+	}
+
+	if err, ok := ctx.getProxy(index).(error); ok {
+		return err.Error()
+	}
+
+	return ctx.Inspect(index)
+}
@@ -0,0 +1,107 @@
+package candyjs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	. "gopkg.in/check.v1"
+)
+
+type consoleRecorder struct {
+	messages []*ConsoleMessage
+}
+
+func (r *consoleRecorder) Log(msg *ConsoleMessage) {
+	r.messages = append(r.messages, msg)
+}
+
+func (s *CandySuite) TestConsole(c *C) {
+	r := &consoleRecorder{}
+	ctx := NewContextWithOptions(Options{Console: r})
+	defer ctx.DestroyHeap()
+
+	ctx.PushString("foo.js")
+	c.Assert(ctx.PcompileStringFilename(0, "\nconsole.warn('foo', 42, {bar: [1]})"), IsNil)
+	c.Assert(ctx.Pcall(0), Equals, 0)
+
+	c.Assert(r.messages, DeepEquals, []*ConsoleMessage{{
+		Level:      slog.LevelWarn,
+		Message:    `foo 42 { bar: [ 1 ] }`,
+		FileName:   "foo.js",
+		LineNumber: 2,
+	}})
+}
+
+func (s *CandySuite) TestConsole_Format(c *C) {
+	r := &consoleRecorder{}
+	ctx := NewContextWithOptions(Options{Console: r})
+	defer ctx.DestroyHeap()
+
+	ctx.PushGlobalProxy("foo", &inspectStruct{Int: 42})
+	ctx.PushGlobalProxy("err", errors.New("qux"))
+
+	provider := [][]string{
+		{`console.log()`, ``},
+		{`console.log('%s: %d%%', 'foo', '42', 'bar')`, `foo: 42% bar`},
+		{`console.log('%i %f %j', 4.2, '4.2', {a: 1})`, `4 4.2 {"a":1}`},
+		{`console.log('%o %s', 'foo')`, `"foo" %s`},
+		{`console.log(42, 'foo', null)`, `42 foo null`},
+		{`console.log(foo)`, `*candyjs.inspectStruct { int: 42, string: "", nested: null, foo() }`},
+		{`console.error('failed:', err)`, `failed: qux`},
+	}
+
+	for _, p := range provider {
+		c.Assert(ctx.PevalString(p[0]), IsNil)
+		c.Assert(r.messages[len(r.messages)-1].Message, Equals, p[1], Commentf("%s", p[0]))
+	}
+}
+
+func (s *CandySuite) TestNewWriterConsole(c *C) {
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	ctx := NewContextWithOptions(Options{Console: NewWriterConsole(stdout, stderr)})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`
+		console.debug('foo');
+		console.info('bar');
+		console.error(new Error('qux'));
+	`), IsNil)
+
+	c.Assert(stdout.String(), Equals, "foo\nbar\n")
+	c.Assert(stderr.String(), Matches, "Error: qux\n(.*\n)+")
+}
+
+func (s *CandySuite) TestNewSlogConsole(c *C) {
+	buf := bytes.NewBuffer(nil)
+	h := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	ctx := NewContextWithOptions(Options{Console: NewSlogConsole(h)})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString("console.debug('foo');\nconsole.warn('bar')"), IsNil)
+
+	var record map[string]interface{}
+	c.Assert(json.Unmarshal(buf.Bytes(), &record), IsNil)
+	c.Assert(record["level"], Equals, "WARN")
+	c.Assert(record["msg"], Equals, "bar")
+	c.Assert(record["filename"], Equals, "eval")
+	c.Assert(record["line"], Equals, 2.0)
+}
+
+func (s *CandySuite) TestPrint(c *C) {
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	ctx := NewContextWithOptions(Options{Stdout: stdout, Stderr: stderr})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`
+		print('foo', 42, [1, 2]);
+		alert('bar');
+		console.log('qux');
+		console.warn('baz');
+	`), IsNil)
+
+	c.Assert(stdout.String(), Equals, "foo 42 1,2\nqux\n")
+	c.Assert(stderr.String(), Equals, "bar\nbaz\n")
+}
@@ -0,0 +1,371 @@
+package candyjs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// encodingStashProp is the property of the global stash holding the object
+// with the encoding globals and module, once is created.
+const encodingStashProp = "candyjsEncoding"
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// pushGlobalEncoding defines the TextEncoder, TextDecoder, atob and btoa
+// globals and the CandyJS.encoding module, all of them are created the first
+// time any of them is used:
+//  - TextEncoder and TextDecoder: as defined by the Encoding Standard, only
+//    for utf-8, the decoder supports the fatal and ignoreBOM options
+//  - atob and btoa: as defined by the HTML Standard
+//  - CandyJS.encoding: the base64, base64url, hex and utf8 objects, with
+//    encode and decode functions, the encode functions accept buffers and
+//    strings, encoded as utf-8, and the decode ones return buffers. The
+//    base64url encoding has no padding, being optional when decoded. The
+//    utf8 object has also the valid function, validating buffers
+func (ctx *Context) pushGlobalEncoding() {
+	ctx.PushGlobalObject()
+	for _, name := range []string{"TextEncoder", "TextDecoder", "atob", "btoa"} {
+		ctx.putEncodingProp(-1, name)
+	}
+
+	ctx.GetPropString(-1, "CandyJS")
+	ctx.putEncodingProp(-1, "encoding")
+	ctx.Pop2()
+}
+
+func (ctx *Context) putEncodingProp(objIndex int, name string) {
+	ctx.PutPropStringLazy(objIndex, name, func() {
+		ctx.pushEncoding()
+		ctx.GetPropString(-1, name)
+		ctx.Remove(-2)
+	})
+}
+
+// pushEncoding pushes the object with the encoding globals and module, created
+// once per Context.
+func (ctx *Context) pushEncoding() {
+	ctx.PushGlobalStash()
+	if ctx.GetPropString(-1, encodingStashProp) {
+		ctx.Remove(-2)
+		return
+	}
+
+	ctx.Pop()
+
+	functions := map[string]func() error{
+		"utf8Encode":   ctx.utf8Encode,
+		"utf8Decode":   ctx.utf8Decode,
+		"utf8Valid":    ctx.utf8Valid,
+		"base64Encode": ctx.base64Encode,
+		"base64Decode": ctx.base64Decode,
+		"hexEncode":    ctx.hexEncode,
+		"hexDecode":    ctx.hexDecode,
+		"atob":         ctx.atob,
+		"btoa":         ctx.btoa,
+	}
+
+	ctx.Context.PevalString(encodingJS)
+	obj := ctx.PushObject()
+	for key, fn := range functions {
+		fn := fn
+		ctx.pushNativeFunction(func(*duktape.Context) int {
+			ctx.SetTop(3)
+			if err := fn(); err != nil {
+				ctx.pushError(err)
+			}
+
+			return 1
+		})
+
+		ctx.PutPropString(obj, key)
+	}
+
+	ctx.Call(1)
+	ctx.Dup(-1)
+	ctx.PutPropString(-3, encodingStashProp)
+	ctx.Remove(-2)
+}
+
+// encodingData returns the bytes of the buffer at the given index, or of the
+// value converted to string, encoded as utf-8.
+func (ctx *Context) encodingData(index int) []byte {
+	if b, ok := ctx.getBuffer(index); ok {
+		return b
+	}
+
+	return []byte(jsToUTF8(ctx.SafeToString(index)))
+}
+
+func (ctx *Context) utf8Encode() error {
+	ctx.PushBytes(ctx.encodingData(0))
+	return nil
+}
+
+func (ctx *Context) utf8Decode() error {
+	b, ok := ctx.getBuffer(0)
+	if !ok {
+		return &Error{Type: "TypeError", Message: "input is not a buffer"}
+	}
+
+	if !ctx.ToBoolean(2) {
+		b = bytes.TrimPrefix(b, utf8BOM)
+	}
+
+	if !utf8.Valid(b) {
+		if ctx.ToBoolean(1) {
+			return &Error{Type: "TypeError", Message: "invalid utf-8 data"}
+		}
+
+		b = replaceInvalidUTF8(b)
+	}
+
+	ctx.PushString(utf8ToJS(string(b)))
+	return nil
+}
+
+func (ctx *Context) utf8Valid() error {
+	b, ok := ctx.getBuffer(0)
+	if !ok {
+		return &Error{Type: "TypeError", Message: "input is not a buffer"}
+	}
+
+	ctx.PushBoolean(utf8.Valid(b))
+	return nil
+}
+
+func (ctx *Context) base64Encode() error {
+	enc := base64.StdEncoding
+	if ctx.ToBoolean(1) {
+		enc = base64.RawURLEncoding
+	}
+
+	ctx.PushString(enc.EncodeToString(ctx.encodingData(0)))
+	return nil
+}
+
+func (ctx *Context) base64Decode() error {
+	enc := base64.RawStdEncoding
+	if ctx.ToBoolean(1) {
+		enc = base64.RawURLEncoding
+	}
+
+	b, err := enc.DecodeString(strings.TrimRight(ctx.SafeToString(0), "="))
+	if err != nil {
+		return &Error{Type: "TypeError", Message: err.Error()}
+	}
+
+	ctx.PushBytes(b)
+	return nil
+}
+
+func (ctx *Context) hexEncode() error {
+	ctx.PushString(hex.EncodeToString(ctx.encodingData(0)))
+	return nil
+}
+
+func (ctx *Context) hexDecode() error {
+	b, err := hex.DecodeString(ctx.SafeToString(0))
+	if err != nil {
+		return &Error{Type: "TypeError", Message: err.Error()}
+	}
+
+	ctx.PushBytes(b)
+	return nil
+}
+
+// atob decodes a base64 string to a string with a character per byte, the
+// whitespace is ignored and the padding optional.
+func (ctx *Context) atob() error {
+	s := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t\n\f\r", r) {
+			return -1
+		}
+
+		return r
+	}, ctx.SafeToString(0))
+
+	if len(s)%4 == 0 {
+		s = strings.TrimSuffix(strings.TrimSuffix(s, "="), "=")
+	}
+
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil || len(s)%4 == 1 {
+		return &Error{Type: "InvalidCharacterError", Message: "invalid base64 string"}
+	}
+
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+
+	ctx.PushString(string(runes))
+	return nil
+}
+
+// btoa encodes a string, containing only characters up to U+00FF, as base64
+// of its characters as bytes.
+func (ctx *Context) btoa() error {
+	var b []byte
+	for _, r := range ctx.SafeToString(0) {
+		if r > 0xff {
+			return &Error{Type: "InvalidCharacterError", Message: "invalid character"}
+		}
+
+		b = append(b, byte(r))
+	}
+
+	ctx.PushString(base64.StdEncoding.EncodeToString(b))
+	return nil
+}
+
+// jsToUTF8 converts a string as stored by Duktape, with the characters
+// outside the BMP encoded as surrogate pairs, to utf-8. The lone surrogates
+// and invalid bytes are replaced by U+FFFD.
+func jsToUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if hi, ok := decodeSurrogate(s[i:]); ok {
+			if lo, ok := decodeSurrogate(s[i+3:]); ok && hi < 0xdc00 && lo >= 0xdc00 {
+				b.WriteRune(utf16.DecodeRune(hi, lo))
+				i += 6
+				continue
+			}
+
+			b.WriteRune(utf8.RuneError)
+			i += 3
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteRune(r)
+		i += size
+	}
+
+	return b.String()
+}
+
+// utf8ToJS converts a valid utf-8 string to the encoding used by Duktape,
+// encoding the characters outside the BMP as surrogate pairs.
+func utf8ToJS(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r > 0xffff }) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if r <= 0xffff {
+			b.WriteRune(r)
+			continue
+		}
+
+		hi, lo := utf16.EncodeRune(r)
+		encodeSurrogate(&b, hi)
+		encodeSurrogate(&b, lo)
+	}
+
+	return b.String()
+}
+
+// decodeSurrogate decodes a surrogate encoded as a three bytes utf-8 sequence
+// at the start of s.
+func decodeSurrogate(s string) (rune, bool) {
+	if len(s) < 3 || s[0] != 0xed || s[1] < 0xa0 || s[1] > 0xbf || s[2]&0xc0 != 0x80 {
+		return 0, false
+	}
+
+	return 0xd000 | rune(s[1]&0x3f)<<6 | rune(s[2]&0x3f), true
+}
+
+func encodeSurrogate(b *strings.Builder, r rune) {
+	b.WriteByte(0xe0 | byte(r>>12))
+	b.WriteByte(0x80 | byte(r>>6)&0x3f)
+	b.WriteByte(0x80 | byte(r)&0x3f)
+}
+
+// replaceInvalidUTF8 replaces every invalid byte by U+FFFD.
+func replaceInvalidUTF8(b []byte) []byte {
+	var out []byte
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		out = utf8.AppendRune(out, r)
+		b = b[size:]
+	}
+
+	return out
+}
+
+const encodingJS = `(function (impl) {
+	function check(v) {
+		if (v instanceof Error) {
+			throw v;
+		}
+
+		return v;
+	}
+
+	function TextEncoder() {}
+
+	TextEncoder.prototype.encoding = 'utf-8';
+	TextEncoder.prototype.encode = function (input) {
+		return impl.utf8Encode(input === undefined ? '' : String(input));
+	};
+
+	var labels = ['utf-8', 'utf8', 'unicode-1-1-utf-8'];
+
+	function TextDecoder(label, options) {
+		label = label === undefined ? 'utf-8' : String(label).trim().toLowerCase();
+		if (labels.indexOf(label) === -1) {
+			throw new RangeError("unsupported encoding '" + label + "'");
+		}
+
+		options = options || {};
+		this.encoding = 'utf-8';
+		this.fatal = !!options.fatal;
+		this.ignoreBOM = !!options.ignoreBOM;
+	}
+
+	TextDecoder.prototype.decode = function (input) {
+		if (input === undefined) {
+			return '';
+		}
+
+		return check(impl.utf8Decode(input, this.fatal, this.ignoreBOM));
+	};
+
+	function base64(url) {
+		return {
+			encode: function (data) { return impl.base64Encode(data, url); },
+			decode: function (s) { return check(impl.base64Decode(s, url)); }
+		};
+	}
+
+	return {
+		TextEncoder: TextEncoder,
+		TextDecoder: TextDecoder,
+		atob: function (s) { return check(impl.atob(s)); },
+		btoa: function (s) { return check(impl.btoa(s)); },
+		encoding: {
+			base64: base64(false),
+			base64url: base64(true),
+			hex: {
+				encode: function (data) { return impl.hexEncode(data); },
+				decode: function (s) { return check(impl.hexDecode(s)); }
+			},
+			utf8: {
+				encode: function (s) { return impl.utf8Encode(s); },
+				decode: function (data) { return check(impl.utf8Decode(data, false, true)); },
+				valid: function (data) { return check(impl.utf8Valid(data)); }
+			}
+		}
+	};
+})`
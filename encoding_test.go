@@ -0,0 +1,78 @@
+package candyjs
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestTextEncoder(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		var buf = new TextEncoder().encode('añ😀');
+		var str = new TextDecoder().decode(buf);
+		[Array.prototype.join.call(buf, ' '), str === 'añ😀', str.length].join()
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "97 195 177 240 159 152 128,true,4")
+}
+
+func (s *CandySuite) TestTextDecoder(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		var bom = new Uint8Array([0xef, 0xbb, 0xbf, 0x61, 0xff]);
+		var fatal;
+		try {
+			new TextDecoder('utf-8', {fatal: true}).decode(bom);
+		} catch (e) {
+			fatal = e.name + ': ' + e.message;
+		}
+
+		[
+			new TextDecoder().decode(bom) === 'a�',
+			new TextDecoder('utf8', {ignoreBOM: true}).decode(bom.buffer).length,
+			fatal
+		].join()
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "true,3,TypeError: invalid utf-8 data")
+	c.Assert(s.ctx.PevalString(`new TextDecoder('latin1')`), ErrorMatches, "RangeError: unsupported encoding 'latin1'.*")
+}
+
+func (s *CandySuite) TestAtobBtoa(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		var binary = atob(' /+/+ ');
+		[btoa('fooÿ'), atob('Zm9v/w=='), binary.length, binary.charCodeAt(0)].join()
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "Zm9v/w==,fooÿ,3,255")
+	c.Assert(s.ctx.PevalString(`btoa('😀')`), ErrorMatches, "InvalidCharacterError: invalid character.*")
+	c.Assert(s.ctx.PevalString(`atob('a')`), ErrorMatches, "InvalidCharacterError: invalid base64 string.*")
+}
+
+func (s *CandySuite) TestEncodingModule(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		var enc = CandyJS.encoding;
+		var data = new Uint8Array([0xfb, 0xff, 0x00]);
+		[
+			enc.base64.encode(data),
+			enc.base64url.encode(data),
+			enc.base64.encode('😀'),
+			enc.base64.decode('+/8A').length,
+			enc.base64url.decode('-_8A=')[1],
+			enc.hex.encode(data),
+			enc.hex.decode('fbff00')[0],
+			enc.utf8.decode(enc.utf8.encode('😀')) === '😀',
+			enc.utf8.valid(data),
+			enc.utf8.valid(enc.utf8.encode('ñ'))
+		].join()
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "+/8A,-_8A,8J+YgA==,3,255,fbff00,251,true,false,true")
+	c.Assert(s.ctx.PevalString(`CandyJS.encoding.hex.decode('zz')`), ErrorMatches, "TypeError: encoding/hex: invalid byte.*")
+}
+
+func (s *CandySuite) TestEncoding_Lazy(c *C) {
+	s.ctx.PushGlobalStash()
+	c.Assert(s.ctx.HasPropString(-1, encodingStashProp), Equals, false)
+
+	c.Assert(s.ctx.PevalString(`typeof btoa + typeof CandyJS.encoding`), IsNil)
+	c.Assert(s.ctx.GetString(-1), Equals, "functionobject")
+	c.Assert(s.ctx.HasPropString(-2, encodingStashProp), Equals, true)
+}
@@ -0,0 +1,84 @@
+package candyjs
+
+import (
+	"fmt"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// Error is a JavaScript error raised during the execution of a script.
+type Error struct {
+	Type       string
+	Message    string
+	FileName   string
+	LineNumber int
+	Stack      string
+}
+
+// Error returns the type and the message of the error, and the file and line
+// where it was raised when are known.
+func (e *Error) Error() string {
+	if e.FileName == "" || e.LineNumber == 0 {
+		return fmt.Sprintf("%s: %s", e.Type, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s (%s:%d)", e.Type, e.Message, e.FileName, e.LineNumber)
+}
+
+// getError returns an Error from the JS error at the given index, the value
+// is keep at the stack.
+func (ctx *Context) getError(index int) *Error {
+	index = ctx.NormalizeIndex(index)
+	if !ctx.IsObject(index) {
+		return &Error{Type: "Error", Message: ctx.SafeToString(index)}
+	}
+
+	err := &Error{}
+	for _, key := range []string{"name", "message", "fileName", "lineNumber", "stack"} {
+		ctx.GetPropString(index, key)
+
+		switch key {
+		case "name":
+			err.Type = ctx.SafeToString(-1)
+		case "message":
+			err.Message = ctx.SafeToString(-1)
+		case "fileName":
+			if ctx.IsString(-1) {
+				err.FileName = ctx.GetString(-1)
+			}
+		case "lineNumber":
+			if ctx.IsNumber(-1) {
+				err.LineNumber = ctx.GetInt(-1)
+			}
+		case "stack":
+			if ctx.IsString(-1) {
+				err.Stack = ctx.GetString(-1)
+			}
+		}
+
+		ctx.Pop()
+	}
+
+	ctx.mapError(err)
+	return err
+}
+
+// pushError pushes the given error as a JS error, keeping the file and line of
+// an Error.
+func (ctx *Context) pushError(err error) {
+	ctx.PushErrorObject(duktape.ErrError, "%s", err.Error())
+
+	e, ok := err.(*Error)
+	if !ok {
+		return
+	}
+
+	ctx.PushString(e.Type)
+	ctx.PutPropString(-2, "name")
+	ctx.PushString(e.Message)
+	ctx.PutPropString(-2, "message")
+	ctx.PushString(e.FileName)
+	ctx.PutPropString(-2, "fileName")
+	ctx.PushInt(e.LineNumber)
+	ctx.PutPropString(-2, "lineNumber")
+}
@@ -0,0 +1,83 @@
+// Package esbuild provides a candyjs.Transformer based on esbuild, a JavaScript
+// transpiler written in Go, allowing the use of newer syntax on Duktape, which
+// only supports ES5.
+//
+//   ctx := candyjs.NewContextWithOptions(candyjs.Options{
+//       Transformer: esbuild.New(),
+//   })
+//
+// esbuild only lowers to ES5 part of the newer syntax: the arrow functions,
+// template literals, exponentiation, optional chaining, nullish coalescing,
+// logical assignments, binary and octal literals and optional catch bindings.
+// Any other syntax is reported as a SyntaxError, including `let`, `const` and
+// classes, and also the destructuring, the array and arguments spread, the
+// rest and default parameters, the shorthand methods and computed keys of the
+// object literals, the for-of loops, the generators and the async functions.
+// The object spread is lowered using Object.getOwnPropertyDescriptors, missing
+// on Duktape, so it fails at runtime. The scripts should use `var` and
+// constructor functions instead.
+package esbuild
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/mcuadros/go-candyjs"
+)
+
+// Transformer is a candyjs.Transformer transpiling the scripts with esbuild.
+type Transformer struct {
+	// Target is the version of the generated JavaScript, ES5 by default.
+	Target api.Target
+}
+
+// New returns a new Transformer targeting ES5, wrapped by a
+// candyjs.TransformCache, so every source is transpiled once.
+func New() *candyjs.TransformCache {
+	return candyjs.NewTransformCache(&Transformer{Target: api.ES5})
+}
+
+// Transform follows the candyjs.Transformer interface, the import and export
+// statements are not transformed, see the ESModules option of candyjs.
+func (t *Transformer) Transform(filename, src string) (string, string, error) {
+	target := t.Target
+	if target == api.DefaultTarget {
+		target = api.ES5
+	}
+
+	r := api.Transform(src, api.TransformOptions{
+		Loader:     api.LoaderJS,
+		Target:     target,
+		Sourcefile: filename,
+		Sourcemap:  api.SourceMapExternal,
+	})
+
+	if len(r.Errors) != 0 {
+		return "", "", newError(filename, r.Errors[0])
+	}
+
+	return string(r.Code), string(r.Map), nil
+}
+
+// unsupportedHint is appended to the errors of the syntax that cannot be lowered
+const unsupportedHint = ", Duktape only supports ES5, use var instead of let " +
+	"and const, and constructor functions instead of classes, see the " +
+	"documentation of the esbuild package for the supported syntax"
+
+func newError(filename string, msg api.Message) error {
+	err := &candyjs.Error{
+		Type:     "SyntaxError",
+		Message:  msg.Text,
+		FileName: filename,
+	}
+
+	if strings.Contains(msg.Text, "is not supported yet") {
+		err.Message += unsupportedHint
+	}
+
+	if msg.Location != nil {
+		err.LineNumber = msg.Location.Line
+	}
+
+	return err
+}
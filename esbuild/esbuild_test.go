@@ -0,0 +1,73 @@
+package esbuild
+
+import (
+	"testing"
+
+	"github.com/mcuadros/go-candyjs"
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type EsbuildSuite struct {
+	ctx *candyjs.Context
+}
+
+var _ = Suite(&EsbuildSuite{})
+
+func (s *EsbuildSuite) SetUpTest(c *C) {
+	s.ctx = candyjs.NewContextWithOptions(candyjs.Options{Transformer: New()})
+}
+
+func (s *EsbuildSuite) TearDownTest(c *C) {
+	s.ctx.DestroyHeap()
+}
+
+func (s *EsbuildSuite) TestTransform(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		var double = (x) => x * 2;
+		var point = {x: 1, y: {z: 2}};
+		var name = 'world';
+		var flags;
+		flags ||= 0b101;
+
+		var caught;
+		try {
+			null.foo;
+		} catch {
+			caught = true;
+		}
+
+		[
+			double(21),
+			`+"`hello ${name}`"+`,
+			2 ** 10,
+			point?.y?.z,
+			point.missing?.z,
+			point.missing ?? 'default',
+			flags,
+			caught,
+		].join()
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "42,hello world,1024,2,,default,5,true")
+}
+
+func (s *EsbuildSuite) TestTransform_Unsupported(c *C) {
+	for _, src := range []string{
+		`let foo = 1`,
+		`const foo = 1`,
+		`class Foo {}`,
+		`var {foo} = {foo: 1}`,
+		`function foo(bar = 1) {}`,
+		`function foo(...bar) {}`,
+		`for (var foo of []) {}`,
+	} {
+		err := s.ctx.PevalString(src)
+		c.Assert(err, FitsTypeOf, &candyjs.Error{}, Commentf(src))
+		c.Assert(err.(*candyjs.Error).Type, Equals, "SyntaxError")
+		c.Assert(err.(*candyjs.Error).Message, Matches, ".*not supported yet.*use var.*", Commentf(src))
+		s.ctx.Pop()
+	}
+}
@@ -0,0 +1,750 @@
+package candyjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// transformESModule transforms the static import and export statements of
+// the given source to the CommonJS style used by the module loader:
+//  - `import x from 'y'` and `import {x as z} from 'y'` are converted to
+//    variables with the value of the exports of the module at the time of the
+//    import, the bindings are not live.
+//  - The exports are defined as getters of the `exports` object at the
+//    beginning of the module, so the exported values are always the current
+//    ones and the hoisted functions are available on cycles.
+//
+// The export statements are only allowed on modules, since the scripts have
+// no `exports` object, a SyntaxError is returned for them otherwise. Only the
+// statements are replaced, keeping the lines of the rest of the source, the
+// returned SourceMap maps the new source to the original one.
+func transformESModule(filename, src string, module bool) (string, *SourceMap, error) {
+	tokens, err := scanESTokens(src)
+	if err != nil {
+		return "", nil, esError(filename, src, err.(*esScanError).pos, err.Error())
+	}
+
+	t := &esTransformer{filename: filename, src: src, tokens: tokens, module: module}
+	if err := t.transform(); err != nil {
+		return "", nil, err
+	}
+
+	return t.build()
+}
+
+type esTransformer struct {
+	filename string
+	src      string
+	module   bool
+	tokens   []esToken
+	edits    []esEdit
+	exports  []esExport
+	exported bool
+	star     bool
+	modules  int
+}
+
+// esEdit replaces the source between start and end by text
+type esEdit struct {
+	start, end int
+	text       string
+}
+
+// esExport is an exported name and the expression returning its value
+type esExport struct {
+	name, value string
+}
+
+func (t *esTransformer) transform() error {
+	var depth int
+	for i := 0; i < len(t.tokens); i++ {
+		tok := t.tokens[i]
+		if tok.kind == esPunct {
+			switch tok.value {
+			case "{", "(", "[":
+				depth++
+			case "}", ")", "]":
+				depth--
+			}
+		}
+
+		if depth != 0 || tok.kind != esIdent || (i > 0 && t.tokens[i-1].is(".")) {
+			continue
+		}
+
+		var end int
+		var err error
+		switch tok.value {
+		case "import":
+			if next := t.token(i + 1); next.is("(") || next.is(".") {
+				continue
+			}
+
+			end, err = t.importStatement(i)
+		case "export":
+			if !t.module {
+				return t.error(i, "export is only supported on modules, not on scripts")
+			}
+
+			end, err = t.exportStatement(i)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		i = end - 1
+	}
+
+	return nil
+}
+
+// importStatement converts the import statement starting at the given token,
+// returning the index of the token following the statement.
+func (t *esTransformer) importStatement(i int) (int, error) {
+	start := i
+	i++
+
+	if tok := t.token(i); tok.kind == esString {
+		end := t.semicolon(i + 1)
+		t.edit(start, end, "require("+tok.value+");")
+		return end, nil
+	}
+
+	module := t.newModule()
+	var vars []string
+
+	bindings := t.token(i).is("{") || t.token(i).is("*")
+	if tok := t.token(i); tok.kind == esIdent && tok.value != "from" {
+		vars = append(vars, fmt.Sprintf(
+			"%s = %s && %s.__esModule ? %s[\"default\"] : %s",
+			tok.value, module, module, module, module,
+		))
+
+		i++
+		if t.token(i).is(",") {
+			bindings = true
+			i++
+		}
+	}
+
+	if bindings {
+		switch tok := t.token(i); {
+		case tok.is("*"):
+			if !t.token(i+1).isIdent("as") || t.token(i+2).kind != esIdent {
+				return 0, t.error(i, "invalid import statement")
+			}
+
+			vars = append(vars, t.token(i+2).value+" = "+module)
+			i += 3
+		case tok.is("{"):
+			specs, next, err := t.specifiers(i)
+			if err != nil {
+				return 0, err
+			}
+
+			for _, s := range specs {
+				vars = append(vars, fmt.Sprintf("%s = %s[%q]", s.alias, module, s.name))
+			}
+
+			i = next
+		default:
+			return 0, t.error(i, "invalid import statement")
+		}
+	}
+
+	if !t.token(i).isIdent("from") || t.token(i+1).kind != esString {
+		return 0, t.error(i, "invalid import statement, expected from")
+	}
+
+	end := t.semicolon(i + 2)
+	text := fmt.Sprintf("var %s = require(%s)", module, t.token(i+1).value)
+	for _, v := range vars {
+		text += ", " + v
+	}
+
+	t.edit(start, end, text+";")
+	return end, nil
+}
+
+// exportStatement converts the export statement starting at the given token,
+// returning the index of the token following the statement.
+func (t *esTransformer) exportStatement(i int) (int, error) {
+	start := i
+	i++
+
+	switch tok := t.token(i); {
+	case tok.is("*"):
+		name := ""
+		if t.token(i + 1).isIdent("as") {
+			name = t.token(i + 2).value
+			i += 2
+		}
+
+		if !t.token(i+1).isIdent("from") || t.token(i+2).kind != esString {
+			return 0, t.error(i, "invalid export statement, expected from")
+		}
+
+		end := t.semicolon(i + 3)
+		module := t.newModule()
+		if name != "" {
+			t.edit(start, end, fmt.Sprintf("var %s = require(%s);", module, t.token(i+2).value))
+			t.export(name, module)
+		} else {
+			t.star = true
+			t.edit(start, end, fmt.Sprintf("var %s = require(%s); __esmExportStar(%s);",
+				module, t.token(i+2).value, module))
+		}
+
+		return end, nil
+	case tok.is("{"):
+		specs, next, err := t.specifiers(i)
+		if err != nil {
+			return 0, err
+		}
+
+		module := ""
+		if t.token(next).isIdent("from") {
+			if t.token(next+1).kind != esString {
+				return 0, t.error(next, "invalid export statement")
+			}
+
+			module = t.newModule()
+			next += 2
+		}
+
+		end := t.semicolon(next)
+		text := ""
+		if module != "" {
+			text = fmt.Sprintf("var %s = require(%s);", module, t.token(next-1).value)
+		}
+
+		t.edit(start, end, text)
+		for _, s := range specs {
+			if module != "" {
+				t.export(s.alias, fmt.Sprintf("%s[%q]", module, s.name))
+			} else {
+				t.export(s.alias, s.name)
+			}
+		}
+
+		return end, nil
+	case tok.isIdent("default"):
+		decl := i + 1
+		if t.token(decl).isIdent("async") {
+			decl++
+		}
+
+		name := t.token(decl + 1)
+		if t.token(decl+1).is("*") {
+			name = t.token(decl + 2)
+		}
+
+		isDecl := t.token(decl).isIdent("function") || t.token(decl).isIdent("class")
+		if isDecl && name.kind == esIdent {
+			t.edit(start, i+1, "")
+			t.export("default", name.value)
+			return i + 1, nil
+		}
+
+		t.edit(start, i+1, "exports[\"default\"] =")
+		t.exported = true
+		return i + 1, nil
+	case tok.isIdent("var"), tok.isIdent("let"), tok.isIdent("const"):
+		t.edit(start, i, "")
+		return t.declarations(i + 1)
+	case tok.isIdent("function"), tok.isIdent("class"), tok.isIdent("async"):
+		decl := i
+		if tok.isIdent("async") {
+			decl++
+		}
+
+		name := t.token(decl + 1)
+		if name.is("*") {
+			name = t.token(decl + 2)
+		}
+
+		if name.kind != esIdent {
+			return 0, t.error(i, "invalid export statement, expected a name")
+		}
+
+		t.edit(start, i, "")
+		t.export(name.value, name.value)
+		return i, nil
+	}
+
+	return 0, t.error(i, "invalid export statement")
+}
+
+// declarations exports the names declared by the variable declaration starting
+// at the given token, returning the index of the token following them.
+func (t *esTransformer) declarations(i int) (int, error) {
+	var depth int
+	expectName := true
+	for ; i < len(t.tokens); i++ {
+		tok := t.tokens[i]
+		if expectName {
+			if tok.kind != esIdent {
+				return 0, t.error(i, "unsupported export declaration")
+			}
+
+			t.export(tok.value, tok.value)
+			expectName = false
+			continue
+		}
+
+		if tok.kind == esPunct {
+			switch tok.value {
+			case "{", "(", "[":
+				depth++
+			case "}", ")", "]":
+				if depth == 0 {
+					return i, nil
+				}
+
+				depth--
+			case ",":
+				expectName = depth == 0
+			case ";":
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+
+			continue
+		}
+
+		// automatic semicolon insertion, on a new line not continuing the
+		// previous expression
+		prev := t.tokens[i-1]
+		if depth == 0 && tok.newline && (prev.kind != esPunct || prev.closes()) {
+			return i, nil
+		}
+	}
+
+	return i, nil
+}
+
+// esSpecifier is an imported or exported name and its alias
+type esSpecifier struct {
+	name, alias string
+}
+
+// specifiers parses the `{a, b as c}` list starting at the given token.
+func (t *esTransformer) specifiers(i int) ([]esSpecifier, int, error) {
+	var specs []esSpecifier
+	for i++; !t.token(i).is("}"); i++ {
+		tok := t.token(i)
+		if tok.kind != esIdent {
+			return nil, 0, t.error(i, "invalid specifier")
+		}
+
+		s := esSpecifier{name: tok.value, alias: tok.value}
+		if t.token(i + 1).isIdent("as") {
+			if t.token(i+2).kind != esIdent {
+				return nil, 0, t.error(i, "invalid specifier")
+			}
+
+			s.alias = t.token(i + 2).value
+			i += 2
+		}
+
+		specs = append(specs, s)
+		if t.token(i + 1).is(",") {
+			i++
+		} else if !t.token(i + 1).is("}") {
+			return nil, 0, t.error(i+1, "invalid specifier list")
+		}
+	}
+
+	return specs, i + 1, nil
+}
+
+func (t *esTransformer) newModule() string {
+	t.modules++
+	return fmt.Sprintf("__esm%d", t.modules)
+}
+
+func (t *esTransformer) export(name, value string) {
+	t.exports = append(t.exports, esExport{name: name, value: value})
+	t.exported = true
+}
+
+// edit replaces the tokens from start to end, not included
+func (t *esTransformer) edit(start, end int, text string) {
+	e := esEdit{start: t.offset(start), end: t.offset(start), text: text}
+	if end > start {
+		e.end = t.tokens[end-1].end
+	}
+
+	t.edits = append(t.edits, e)
+}
+
+// semicolon returns the index of the token following the optional semicolon
+// at the given index.
+func (t *esTransformer) semicolon(i int) int {
+	if t.token(i).is(";") {
+		return i + 1
+	}
+
+	return i
+}
+
+func (t *esTransformer) token(i int) esToken {
+	if i >= len(t.tokens) {
+		return esToken{kind: esEOF, start: len(t.src), end: len(t.src)}
+	}
+
+	return t.tokens[i]
+}
+
+func (t *esTransformer) offset(i int) int {
+	return t.token(i).start
+}
+
+func (t *esTransformer) error(i int, msg string) error {
+	return esError(t.filename, t.src, t.offset(i), msg)
+}
+
+// build applies the edits and adds the definition of the exports at the
+// beginning of the source.
+func (t *esTransformer) build() (string, *SourceMap, error) {
+	var preamble []string
+	if t.exported || t.star {
+		preamble = append(preamble, `Object.defineProperty(exports, "__esModule", {value: true});`)
+	}
+
+	for _, e := range t.exports {
+		preamble = append(preamble, fmt.Sprintf(
+			"Object.defineProperty(exports, %q, {enumerable: true, get: function () { return %s; }});",
+			e.name, e.value,
+		))
+	}
+
+	if t.star {
+		preamble = append(preamble, esExportStar)
+	}
+
+	w := &esWriter{m: &SourceMap{Sources: []string{t.filename}}, src: t.src}
+	if len(preamble) != 0 {
+		w.replace(0, 0, strings.Join(preamble, " ")+" ")
+	}
+
+	var pos int
+	for _, e := range t.edits {
+		w.copy(pos, e.start)
+		w.replace(e.start, e.end, e.text)
+		pos = e.end
+	}
+
+	w.copy(pos, len(t.src))
+	return w.b.String(), w.m, nil
+}
+
+const esExportStar = `function __esmExportStar(m) {` +
+	` Object.keys(m).forEach(function (k) {` +
+	` if (k === "default" || k === "__esModule" || Object.prototype.hasOwnProperty.call(exports, k)) { return; }` +
+	` Object.defineProperty(exports, k, {enumerable: true, get: function () { return m[k]; }}); }); }`
+
+// esWriter writes the transformed source, building its SourceMap
+type esWriter struct {
+	b            strings.Builder
+	m            *SourceMap
+	src          string
+	line, column int
+}
+
+// copy copies the original source between start and end
+func (w *esWriter) copy(start, end int) {
+	if start == end {
+		return
+	}
+
+	line, column := position(w.src, start)
+	w.m.add(w.line, w.column, 0, line, column)
+	for _, c := range w.src[start:end] {
+		w.b.WriteRune(c)
+		w.column++
+		if c == '\n' {
+			line++
+			w.line, w.column = w.line+1, 0
+			w.m.add(w.line, 0, 0, line, 0)
+		}
+	}
+}
+
+// replace writes text instead of the original source between start and end,
+// keeping its line breaks.
+func (w *esWriter) replace(start, end int, text string) {
+	line, column := position(w.src, start)
+	w.m.add(w.line, w.column, 0, line, column)
+	w.b.WriteString(text)
+	w.column += len(text)
+
+	for i := 0; i < strings.Count(w.src[start:end], "\n"); i++ {
+		line++
+		w.b.WriteString("\n")
+		w.line, w.column = w.line+1, 0
+		w.m.add(w.line, 0, 0, line, 0)
+	}
+}
+
+// position returns the zero-based line and column of the given offset
+func position(src string, offset int) (line, column int) {
+	line = strings.Count(src[:offset], "\n")
+	column = offset - strings.LastIndex(src[:offset], "\n") - 1
+	return line, column
+}
+
+func esError(filename, src string, offset int, msg string) error {
+	line, _ := position(src, offset)
+	return &Error{
+		Type:       "SyntaxError",
+		Message:    msg,
+		FileName:   filename,
+		LineNumber: line + 1,
+	}
+}
+
+type esTokenKind int
+
+const (
+	esEOF esTokenKind = iota
+	esIdent
+	esString
+	esPunct
+	esOther
+)
+
+type esToken struct {
+	kind       esTokenKind
+	value      string
+	start, end int
+	// newline is true if the token is preceded by a line break
+	newline bool
+}
+
+func (t esToken) is(punct string) bool {
+	return t.kind == esPunct && t.value == punct
+}
+
+func (t esToken) isIdent(name string) bool {
+	return t.kind == esIdent && t.value == name
+}
+
+func (t esToken) closes() bool {
+	return t.is(")") || t.is("]") || t.is("}")
+}
+
+type esScanError struct {
+	pos int
+	msg string
+}
+
+func (e *esScanError) Error() string {
+	return e.msg
+}
+
+// regexpKeywords are the keywords after which a slash starts a regexp
+var regexpKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+// scanESTokens splits the source in tokens, enough to find the import and
+// export statements: the comments are skipped and the strings, templates and
+// regexps are read as a whole.
+func scanESTokens(src string) ([]esToken, error) {
+	var tokens []esToken
+	// templates contains, for every open brace, if opens a substitution
+	var templates []bool
+	var newline bool
+
+	emit := func(kind esTokenKind, start, end int) {
+		tokens = append(tokens, esToken{
+			kind: kind, value: src[start:end], start: start, end: end,
+			newline: newline,
+		})
+
+		newline = false
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			newline = true
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return nil, &esScanError{i, "unterminated comment"}
+			}
+
+			if strings.Contains(src[i:i+2+end], "\n") {
+				newline = true
+			}
+
+			i += end + 4
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+
+			emit(esIdent, start, i)
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+
+			emit(esOther, start, i)
+		case c == '"' || c == '\'':
+			end, ok := scanQuoted(src, i, c)
+			if !ok {
+				return nil, &esScanError{i, "unterminated string"}
+			}
+
+			emit(esString, i, end)
+			i = end
+		case c == '`':
+			end, ok := scanTemplate(src, i+1)
+			if !ok {
+				return nil, &esScanError{i, "unterminated template"}
+			}
+
+			if strings.HasSuffix(src[i:end], "${") {
+				templates = append(templates, true)
+			}
+
+			emit(esOther, i, end)
+			i = end
+		case c == '}' && len(templates) != 0 && templates[len(templates)-1]:
+			templates = templates[:len(templates)-1]
+			end, ok := scanTemplate(src, i+1)
+			if !ok {
+				return nil, &esScanError{i, "unterminated template"}
+			}
+
+			if strings.HasSuffix(src[i:end], "${") {
+				templates = append(templates, true)
+			}
+
+			emit(esOther, i, end)
+			i = end
+		case c == '/' && allowsRegexp(tokens):
+			end, ok := scanRegexp(src, i)
+			if !ok {
+				return nil, &esScanError{i, "unterminated regexp"}
+			}
+
+			emit(esOther, i, end)
+			i = end
+		default:
+			switch c {
+			case '{':
+				templates = append(templates, false)
+			case '}':
+				if len(templates) != 0 {
+					templates = templates[:len(templates)-1]
+				}
+			}
+
+			emit(esPunct, i, i+1)
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' ||
+		c == '\\' || c >= 0x80
+}
+
+func allowsRegexp(tokens []esToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+
+	prev := tokens[len(tokens)-1]
+	switch prev.kind {
+	case esPunct:
+		return !prev.is(")") && !prev.is("]")
+	case esIdent:
+		return regexpKeywords[prev.value]
+	}
+
+	return false
+}
+
+// scanQuoted returns the end of the string starting at the given offset
+func scanQuoted(src string, i int, quote byte) (int, bool) {
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, true
+		case '\n':
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
+
+// scanTemplate returns the end of the template chunk starting at the given
+// offset, ending by a backtick or the start of a substitution.
+func scanTemplate(src string, i int) (int, bool) {
+	for ; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '`':
+			return i + 1, true
+		case strings.HasPrefix(src[i:], "${"):
+			return i + 2, true
+		}
+	}
+
+	return 0, false
+}
+
+func scanRegexp(src string, i int) (int, bool) {
+	var class bool
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '\n':
+			return 0, false
+		case '/':
+			if class {
+				continue
+			}
+
+			for i++; i < len(src) && isIdentStart(src[i]); i++ {
+			}
+
+			return i, true
+		}
+	}
+
+	return 0, false
+}
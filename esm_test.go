@@ -0,0 +1,170 @@
+package candyjs
+
+import (
+	"testing/fstest"
+
+	. "gopkg.in/check.v1"
+)
+
+var esModulesFS = fstest.MapFS{
+	"main.js": {Data: []byte(`
+		import math, {double as twice, PI} from './math';
+		import * as all from './math.js';
+		import util from './util';
+		import './side';
+
+		store([math.name, twice(21), PI, all.triple(2), util.foo, globalEffect]);
+	`)},
+	"math.js": {Data: []byte(`
+		export function double(x) {
+			return x * 2;
+		}
+
+		export var PI = 3.14, E = 2.71;
+		export { triple };
+		export default { name: 'math' };
+
+		function triple(x) { return x * 3; }
+	`)},
+	"util.js": {Data: []byte(`module.exports = { foo: 'bar' };`)},
+	"side.js": {Data: []byte(`globalEffect = true;`)},
+	"reexport.js": {Data: []byte(`
+		export * from './math';
+		export { double as twice } from './math';
+		export * as ns from './math';
+	`)},
+	"live.js": {Data: []byte(`
+		export var counter = 0;
+		export function increment() { counter++; }
+	`)},
+	"cycle-a.js": {Data: []byte(`
+		import { b } from './cycle-b';
+		export function a() { return 'a'; }
+		export var fromB = b();
+	`)},
+	"cycle-b.js": {Data: []byte(`
+		import * as modA from './cycle-a';
+		export function b() { return typeof modA.a; }
+	`)},
+	"error.js": {Data: []byte(`import {
+			foo
+		} from './util';
+
+		export default function fail() {
+			throw new Error('foo');
+		}
+	`)},
+	"invalid.js": {Data: []byte(`
+		export { foo as };
+	`)},
+}
+
+func (s *CandySuite) newESContext() *Context {
+	ctx := NewContextWithOptions(Options{FS: esModulesFS, ESModules: true})
+	ctx.PushGlobalGoFunction("store", func(a interface{}) {
+		s.stored = a
+	})
+
+	return ctx
+}
+
+func (s *CandySuite) TestESModules(c *C) {
+	ctx := s.newESContext()
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalFile("main.js"), IsNil)
+	c.Assert(s.stored, DeepEquals, []interface{}{
+		"math", 42.0, 3.14, 6.0, "bar", true,
+	})
+}
+
+func (s *CandySuite) TestESModules_Reexport(c *C) {
+	ctx := s.newESContext()
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`
+		var m = require('./reexport');
+		store([m.double(1), m.twice(2), m.ns.triple(3), m.E, m['default']])
+	`), IsNil)
+	c.Assert(s.stored, DeepEquals, []interface{}{2.0, 4.0, 9.0, 2.71, nil})
+}
+
+func (s *CandySuite) TestESModules_Live(c *C) {
+	ctx := s.newESContext()
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`
+		var live = require('./live');
+		live.increment();
+		live.increment();
+		store([live.counter, Object.keys(live)])
+	`), IsNil)
+	c.Assert(s.stored, DeepEquals, []interface{}{
+		2.0, []interface{}{"counter", "increment"},
+	})
+}
+
+func (s *CandySuite) TestESModules_Cycle(c *C) {
+	ctx := s.newESContext()
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`store(require('./cycle-a').fromB)`), IsNil)
+	c.Assert(s.stored, Equals, "function")
+}
+
+func (s *CandySuite) TestESModules_Error(c *C) {
+	ctx := s.newESContext()
+	defer ctx.DestroyHeap()
+
+	ctx.PevalString(`require('./error')['default']()`)
+	err := ctx.getError(-1)
+	c.Assert(err.Message, Equals, "foo")
+	c.Assert(err.FileName, Equals, "error.js")
+	c.Assert(err.LineNumber, Equals, 6)
+	c.Assert(err.Stack, Matches, "(?s).*\\(error.js:6\\).*")
+}
+
+func (s *CandySuite) TestESModules_SyntaxError(c *C) {
+	ctx := s.newESContext()
+	defer ctx.DestroyHeap()
+
+	ctx.PevalString(`require('./invalid')`)
+	err := ctx.getError(-1)
+	c.Assert(err.Type, Equals, "SyntaxError")
+	c.Assert(err.FileName, Equals, "invalid.js")
+	c.Assert(err.LineNumber, Equals, 2)
+}
+
+func (s *CandySuite) TestESModules_ExportOnScript(c *C) {
+	ctx := s.newESContext()
+	defer ctx.DestroyHeap()
+
+	err := ctx.PevalFile("math.js")
+	c.Assert(err, NotNil)
+	c.Assert(err.(*Error).Type, Equals, "SyntaxError")
+	c.Assert(err.(*Error).Message, Equals, "export is only supported on modules, not on scripts")
+	c.Assert(err.(*Error).FileName, Equals, "math.js")
+	c.Assert(err.(*Error).LineNumber, Equals, 2)
+
+	_, err = ctx.Compile("math.js", `var foo = 1;
+		export { foo };`)
+	c.Assert(err.(*Error).LineNumber, Equals, 2)
+}
+
+func (s *CandySuite) TestTransformESModule(c *C) {
+	src := "// import foo from 'bar'\nvar s = 'export default 1', r = /import/;\n" +
+		"var t = `${ {a: 1}.a } import x from 'y'`;\n" +
+		"import {\n  a\n} from 'a'; a()\n"
+
+	code, m, err := transformESModule("foo.js", src, true)
+	c.Assert(err, IsNil)
+	c.Assert(code, Equals, "// import foo from 'bar'\nvar s = 'export default 1', r = /import/;\n"+
+		"var t = `${ {a: 1}.a } import x from 'y'`;\n"+
+		"var __esm1 = require('a'), a = __esm1[\"a\"];\n\n a()\n")
+
+	source, line, column, ok := m.Position(6, 2)
+	c.Assert(ok, Equals, true)
+	c.Assert(source, Equals, "foo.js")
+	c.Assert(line, Equals, 6)
+	c.Assert(column, Equals, 11)
+}
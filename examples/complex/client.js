@@ -1,15 +1,14 @@
-var http = CandyJS.require('net/http');
-var ioutil = CandyJS.require('io/ioutil');
-
-resp = http.get('http://localhost:8080/back');
-
-if (resp.statusCode == 200) {
-  var json = ioutil.readAll(resp.body);
-  var obj = JSON.parse(json);
+fetch('http://localhost:8080/back').then(function(resp) {
+  if (!resp.ok) {
+    resp.body.close();
+    throw new Error('Request failed, status code: ' + resp.status);
+  }
 
+  return resp.json();
+}).then(function(obj) {
   print('Back to the future date:', obj.future);
   print('Current date:', obj.future);
   print('Back to the Future day is on: ' + obj.nsecs + ' nsecs!');
-} else {
-  print('Request failed, status code:', resp.statusCode);
-}
\ No newline at end of file
+}).catch(function(err) {
+  print(err.message);
+});
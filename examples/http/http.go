@@ -1,24 +1,32 @@
 package main
 
 import (
-	"io"
 	"net/http"
+	"time"
 
 	"github.com/mcuadros/go-candyjs"
 )
 
 func main() {
-	ctx := candyjs.NewContext()
-	ctx.PushGlobalGoFunction("handleFunc", http.HandleFunc)
-	ctx.PushGlobalGoFunction("listenAndServe", http.ListenAndServe)
-	ctx.PushGlobalGoFunction("writeString", io.WriteString)
+	pool, err := candyjs.NewPool(candyjs.PoolConfig{
+		Setup: func(ctx *candyjs.Context) error {
+			return ctx.PevalString(`
+                function handler(req, res) {
+                    res.setHeader('Content-Type', 'text/plain');
+                    res.end('Hello from CandyJS!');
+                }
+            `)
+		},
+		Max: 4,
+	})
 
-	ctx.EvalString(`
-        handler = function(writer, request) {
-            writeString(writer, "Hello from CandyJS!")
-        }
+	if err != nil {
+		panic(err)
+	}
 
-        handleFunc("/", CandyJS.proxy(handler))
-        listenAndServe(":8000", null)
-    `)
+	h := candyjs.Handler(pool, "handler")
+	h.Timeout = 5 * time.Second
+
+	http.Handle("/", h)
+	http.ListenAndServe(":8000", nil)
 }
@@ -0,0 +1,277 @@
+package candyjs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// ErrInvalidBody is returned reading the body of a Response of fetch that is
+// not a io.ReadCloser.
+var ErrInvalidBody = errors.New("invalid body")
+
+// pushGlobalFetch defines the `fetch` global, doing the requests with the
+// given RoundTripper. The redirects are followed unless the `redirect` option
+// is "manual".
+//
+// Duktape has no Promise, so fetch and the methods reading the body of the
+// Response return Promise-like objects, with `then` and `catch`, settled
+// before they are returned. The bodies not read are closed when the Response
+// is garbage collected, `response.body.close()` closes them before. The
+// requests are cancelled when the Context is interrupted, like by the deadline
+// of Pool.Do.
+func (ctx *Context) pushGlobalFetch(rt http.RoundTripper) {
+	follow := &http.Client{Transport: rt}
+	manual := &http.Client{Transport: rt, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	ctx.Context.PevalString(fetchJS)
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		req, err := ctx.fetchRequest(ctx.GetString(0), 1)
+		if err != nil {
+			ctx.pushError(err)
+			return 1
+		}
+
+		client := follow
+		if ctx.getStringProp(1, "redirect") == "manual" {
+			client = manual
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			ctx.pushError(err)
+			return 1
+		}
+
+		ctx.pushFetchResponse(req, resp)
+		return 1
+	})
+
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		body, _ := ctx.getProxy(0).(io.ReadCloser)
+		if body == nil {
+			ctx.pushError(ErrInvalidBody)
+			return 1
+		}
+
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			ctx.pushError(err)
+			return 1
+		}
+
+		if ctx.GetBoolean(1) {
+			ctx.PushString(string(data))
+		} else {
+			ctx.PushBytes(data)
+		}
+
+		return 1
+	})
+
+	ctx.Call(2)
+	ctx.PushGlobalObject()
+	ctx.Swap(-2, -1)
+	ctx.PutPropString(-2, "fetch")
+	ctx.Pop()
+}
+
+// fetchRequest returns the request to the given url with the method, headers
+// and body of the init object at the given index. The body can be a string, a
+// buffer or a proxied io.Reader.
+func (ctx *Context) fetchRequest(url string, init int) (*http.Request, error) {
+	method := ctx.getStringProp(init, "method")
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	ctx.GetPropString(init, "body")
+	if r, ok := ctx.getProxy(-1).(io.Reader); ok {
+		body = r
+	} else if b, ok := ctx.getBuffer(-1); ok {
+		body = bytes.NewReader(b)
+	} else if !ctx.IsNullOrUndefined(-1) {
+		body = strings.NewReader(ctx.SafeToString(-1))
+	}
+
+	ctx.Pop()
+
+	req, err := http.NewRequestWithContext(ctx.done, strings.ToUpper(method), url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.GetPropString(init, "headers")
+	defer ctx.Pop()
+
+	if ctx.IsObject(-1) {
+		ctx.Enum(-1, duktape.EnumOwnPropertiesOnly)
+		for ctx.Next(-1, true) {
+			req.Header.Add(ctx.SafeToString(-2), ctx.SafeToString(-1))
+			ctx.Pop2()
+		}
+
+		ctx.Pop()
+	}
+
+	return req, nil
+}
+
+// pushFetchResponse pushes the fields of the Response, with the header names
+// in lower case and the body as a proxied io.ReadCloser, closed when is read,
+// or when is garbage collected.
+func (ctx *Context) pushFetchResponse(req *http.Request, resp *http.Response) {
+	url := req.URL.String()
+	if resp.Request != nil {
+		url = resp.Request.URL.String()
+	}
+
+	obj := ctx.PushObject()
+	ctx.PushInt(resp.StatusCode)
+	ctx.PutPropString(obj, "status")
+	ctx.PushString(http.StatusText(resp.StatusCode))
+	ctx.PutPropString(obj, "statusText")
+	ctx.PushString(url)
+	ctx.PutPropString(obj, "url")
+	ctx.PushBoolean(url != req.URL.String())
+	ctx.PutPropString(obj, "redirected")
+
+	ctx.pushHeaders(resp.Header)
+	ctx.PutPropString(obj, "headers")
+	ctx.pushClosingProxy(resp.Body)
+	ctx.PutPropString(obj, "body")
+}
+
+// pushHeaders pushes an object with the given headers, the names are in lower
+// case and the values of the same header joined by commas.
+func (ctx *Context) pushHeaders(h http.Header) {
+	ctx.PushObject()
+	for name, values := range h {
+		ctx.PushString(strings.Join(values, ", "))
+		ctx.PutPropString(-2, strings.ToLower(name))
+	}
+}
+
+// getStringProp returns the string at the given property of the object at
+// the given index, or an empty string if is not an object or a string.
+func (ctx *Context) getStringProp(index int, name string) string {
+	if !ctx.IsObject(index) {
+		return ""
+	}
+
+	ctx.GetPropString(index, name)
+	defer ctx.Pop()
+
+	if !ctx.IsString(-1) {
+		return ""
+	}
+
+	return ctx.GetString(-1)
+}
+
+const fetchJS = `(function (doFetch, readBody) {
+	function check(v) {
+		if (v instanceof Error) {
+			throw v;
+		}
+
+		return v;
+	}
+
+	function settled(ok, value) {
+		return {
+			then: function (onFulfilled, onRejected) {
+				var next = ok ? onFulfilled : onRejected;
+				if (typeof next !== 'function') {
+					return this;
+				}
+
+				return settle(function () { return next(value); });
+			},
+			'catch': function (onRejected) {
+				return this.then(null, onRejected);
+			}
+		};
+	}
+
+	function settle(fn) {
+		try {
+			var value = fn();
+			if (value && typeof value.then === 'function') {
+				return value;
+			}
+
+			return settled(true, value);
+		} catch (e) {
+			return settled(false, e);
+		}
+	}
+
+	function Headers(map) {
+		this._map = map;
+	}
+
+	Headers.prototype.get = function (name) {
+		var value = this._map[String(name).toLowerCase()];
+		return value === undefined ? null : value;
+	};
+
+	Headers.prototype.has = function (name) {
+		return this._map.hasOwnProperty(String(name).toLowerCase());
+	};
+
+	Headers.prototype.forEach = function (fn, thisArg) {
+		for (var name in this._map) {
+			fn.call(thisArg, this._map[name], name, this);
+		}
+	};
+
+	function Response(r) {
+		this.status = r.status;
+		this.statusText = r.statusText;
+		this.ok = r.status >= 200 && r.status < 300;
+		this.url = r.url;
+		this.redirected = r.redirected;
+		this.headers = new Headers(r.headers);
+		this.body = r.body;
+		this.bodyUsed = false;
+	}
+
+	Response.prototype._read = function (text) {
+		var self = this;
+		return settle(function () {
+			if (self.bodyUsed) {
+				throw new TypeError('body already used');
+			}
+
+			self.bodyUsed = true;
+			return check(readBody(self.body, text));
+		});
+	};
+
+	Response.prototype.text = function () {
+		return this._read(true);
+	};
+
+	Response.prototype.json = function () {
+		return this._read(true).then(function (text) { return JSON.parse(text); });
+	};
+
+	Response.prototype.arrayBuffer = function () {
+		return this._read(false).then(function (buf) { return buf.buffer; });
+	};
+
+	return function fetch(url, init) {
+		return settle(function () {
+			return new Response(check(doFetch(String(url), init || {})));
+		});
+	};
+})`
@@ -0,0 +1,146 @@
+package candyjs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestFetch(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"foo": "bar"}`)
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Method", r.Method)
+			w.Header().Set("X-Foo", r.Header.Get("X-Foo"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case "/redirect":
+			http.Redirect(w, r, "/json", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := NewContextWithOptions(Options{Fetch: http.DefaultTransport})
+	defer ctx.DestroyHeap()
+
+	ctx.PushGlobalInterface("url", server.URL)
+	c.Assert(ctx.PevalString(`
+		var result = [];
+		fetch(url + '/json').then(function (res) {
+			result.push(res.status, res.ok, res.headers.get('Content-Type'));
+			return res.json();
+		}).then(function (obj) {
+			result.push(obj.foo);
+		});
+
+		fetch(url + '/echo', {
+			method: 'post',
+			headers: {'X-Foo': 'qux'},
+			body: new Uint8Array([102, 111, 111])
+		}).then(function (res) {
+			result.push(res.status, res.statusText, res.headers.get('x-method'), res.headers.get('x-foo'));
+			return res.arrayBuffer();
+		}).then(function (buf) {
+			result.push(buf.byteLength);
+		});
+
+		fetch(url + '/redirect').then(function (res) {
+			result.push(res.redirected, res.url === url + '/json');
+			res.body.close();
+		});
+
+		fetch(url + '/redirect', {redirect: 'manual'}).then(function (res) {
+			result.push(res.status, res.headers.get('location'));
+			return res.text();
+		});
+
+		fetch(url + '/missing').then(function (res) {
+			result.push(res.ok, res.status);
+			return res.text().then(function () { return res.text(); });
+		}).catch(function (err) {
+			result.push(err.name);
+		});
+
+		result.join()
+	`), IsNil)
+
+	c.Assert(ctx.GetString(-1), Equals, "200,true,application/json,bar,"+
+		"201,Created,POST,qux,3,"+
+		"true,true,"+
+		"302,/json,"+
+		"false,404,TypeError")
+}
+
+func (s *CandySuite) TestFetch_Error(c *C) {
+	ctx := NewContextWithOptions(Options{Fetch: http.DefaultTransport})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`
+		var error;
+		fetch('foo://bar').catch(function (err) {
+			error = err.message;
+		});
+
+		error
+	`), IsNil)
+
+	c.Assert(ctx.GetString(-1), Matches, `.*unsupported protocol scheme.*`)
+}
+
+func (s *CandySuite) TestFetch_Disabled(c *C) {
+	c.Assert(s.ctx.PevalString(`typeof fetch`), IsNil)
+	c.Assert(s.ctx.GetString(-1), Equals, "undefined")
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+type recorderTransport struct {
+	bodies []*closeRecorder
+}
+
+func (t *recorderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := &closeRecorder{Reader: strings.NewReader("foo")}
+	t.bodies = append(t.bodies, body)
+
+	return &http.Response{StatusCode: http.StatusOK, Body: body, Request: req}, nil
+}
+
+func (s *CandySuite) TestFetch_CloseUnread(c *C) {
+	t := &recorderTransport{}
+	ctx := NewContextWithOptions(Options{Fetch: t})
+
+	c.Assert(ctx.PevalString(`
+		fetch('http://example.com/unread');
+		fetch('http://example.com/read').then(function (res) { return res.text(); });
+		Duktape.gc();
+	`), IsNil)
+
+	c.Assert(t.bodies, HasLen, 2)
+	ctx.releaseStorage()
+	c.Assert(t.bodies[0].closed, Equals, true)
+	c.Assert(t.bodies[1].closed, Equals, true)
+
+	c.Assert(ctx.PevalString(`var res; fetch('http://example.com/kept').then(function (r) { res = r; })`), IsNil)
+	ctx.releaseStorage()
+	c.Assert(t.bodies[2].closed, Equals, false)
+
+	ctx.DestroyHeap()
+	c.Assert(t.bodies[2].closed, Equals, true)
+}
@@ -0,0 +1,62 @@
+package candyjs
+
+import (
+	"io/fs"
+	"io/ioutil"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// PevalFile evaluates the given file, read from the FS given at Options or
+// from the OS file system. As PevalString does, the result or the error is
+// left on the stack.
+func (ctx *Context) PevalFile(filename string) error {
+	if err := ctx.pcompileFile(filename); err != nil {
+		return err
+	}
+
+	if ctx.Pcall(0) != 0 {
+		return ctx.getError(-1)
+	}
+
+	return nil
+}
+
+// EvalFile like PevalFile but panics on error.
+func (ctx *Context) EvalFile(filename string) {
+	if err := ctx.PevalFile(filename); err != nil {
+		ctx.Pop()
+		panic(err)
+	}
+}
+
+// pcompileFile pushes the given file compiled as eval code, the errors are
+// pushed and returned.
+func (ctx *Context) pcompileFile(filename string) error {
+	content, err := ctx.readFile(filename)
+	if err != nil {
+		ctx.pushError(err)
+		return err
+	}
+
+	src, err := ctx.transform(filename, string(content))
+	if err != nil {
+		ctx.pushError(err)
+		return err
+	}
+
+	ctx.PushString(filename)
+	if err := ctx.PcompileStringFilename(duktape.CompileEval, src); err != nil {
+		return ctx.getError(-1)
+	}
+
+	return nil
+}
+
+func (ctx *Context) readFile(filename string) ([]byte, error) {
+	if ctx.fs == nil {
+		return ioutil.ReadFile(filename)
+	}
+
+	return fs.ReadFile(ctx.fs, filename)
+}
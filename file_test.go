@@ -0,0 +1,69 @@
+package candyjs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "gopkg.in/check.v1"
+)
+
+var scriptsFS = fstest.MapFS{
+	"main.js":    {Data: []byte(`CandyJS.include('lib/foo.js'); foo(21)`)},
+	"lib/foo.js": {Data: []byte(`function foo(x) { return x * 2; }`)},
+	"module.js":  {Data: []byte(`require('./lib/bar').bar`)},
+	"lib/bar.js": {Data: []byte(`exports.bar = 'qux';`)},
+	"error.js":   {Data: []byte("\n\nthrow new Error('foo');")},
+}
+
+func (s *CandySuite) TestPevalFile(c *C) {
+	ctx := NewContextWithOptions(Options{FS: scriptsFS})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalFile("main.js"), IsNil)
+	c.Assert(ctx.GetNumber(-1), Equals, 42.0)
+
+	c.Assert(ctx.PevalFile("module.js"), IsNil)
+	c.Assert(ctx.GetString(-1), Equals, "qux")
+}
+
+func (s *CandySuite) TestPevalFile_Error(c *C) {
+	ctx := NewContextWithOptions(Options{FS: scriptsFS})
+	defer ctx.DestroyHeap()
+
+	err := ctx.PevalFile("error.js")
+	c.Assert(err, FitsTypeOf, &Error{})
+	c.Assert(err.(*Error).FileName, Equals, "error.js")
+	c.Assert(err.(*Error).LineNumber, Equals, 3)
+
+	err = ctx.PevalFile("missing.js")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	c.Assert(ctx.IsError(-1), Equals, true)
+
+	c.Assert(ctx.PevalString(`CandyJS.include('missing.js')`), ErrorMatches, ".*missing.js.*")
+}
+
+func (s *CandySuite) TestPevalFile_OS(c *C) {
+	dir := c.MkDir()
+	filename := filepath.Join(dir, "foo.js")
+	c.Assert(ioutil.WriteFile(filename, []byte(`store('qux')`), 0644), IsNil)
+
+	c.Assert(s.ctx.PevalFile(filename), IsNil)
+	c.Assert(s.stored, Equals, "qux")
+
+	c.Assert(s.ctx.PevalString(`typeof CandyJS.include`), IsNil)
+	c.Assert(s.ctx.GetString(-1), Equals, "undefined")
+}
+
+func (s *CandySuite) TestPool_Options(c *C) {
+	p, err := NewPool(PoolConfig{Max: 1, Options: Options{FS: scriptsFS}})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	ctx, err := p.Get()
+	c.Assert(err, IsNil)
+
+	c.Assert(ctx.PevalFile("main.js"), IsNil)
+	c.Assert(ctx.GetNumber(-1), Equals, 42.0)
+}
@@ -0,0 +1,364 @@
+package candyjs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// ErrReadOnly is returned by the write operations of a read-only FileSystem.
+var ErrReadOnly = errors.New("read-only file system")
+
+// FileSystem configures the `fs` module, giving to the scripts access to the
+// files of a directory or a fs.FS. The paths are relative to the root, even
+// the ones starting by `/`, and cannot point outside it, neither using `..`
+// nor symbolic links.
+type FileSystem struct {
+	// Dir is the root directory of the module, the files are read and
+	// written using an os.Root.
+	Dir string
+	// FS is used as root when Dir is empty, being read-only.
+	FS fs.FS
+	// ReadOnly disables writeFile, mkdir, remove and the opening of files
+	// for writing.
+	ReadOnly bool
+}
+
+// pushModule pushes the object of the `fs` module, with the functions:
+//  - readFile(path[, encoding]): returns the content as a buffer, or as a
+//    string if the encoding is "utf8"
+//  - writeFile(path, data): writes a buffer or a string, creating the file
+//  - readDir(path): returns the names of the entries of a directory, sorted
+//  - stat(path): returns an object with the name, size, mode, modTime and
+//    isDir of the file
+//  - mkdir(path[, recursive]): creates a directory, and its parents if
+//    recursive
+//  - remove(path[, recursive]): removes a file or a directory, and its
+//    content if recursive
+//  - open(path[, flags]): returns the file as a stream, the flags are "r",
+//    "r+", "w", "w+", "a" and "a+", as the fopen ones. The file should be
+//    closed, otherwise is closed when is garbage collected
+//
+// The errors are thrown as errors with the message of the Go error.
+func (f *FileSystem) pushModule(ctx *Context) {
+	functions := map[string]func(name string) error{
+		"readFile":  func(name string) error { return f.readFile(ctx, name) },
+		"writeFile": func(name string) error { return f.writeFile(ctx, name) },
+		"readDir":   func(name string) error { return f.readDir(ctx, name) },
+		"stat":      func(name string) error { return f.stat(ctx, name) },
+		"mkdir":     func(name string) error { return f.mkdir(ctx, name) },
+		"remove":    func(name string) error { return f.remove(ctx, name) },
+		"open":      func(name string) error { return f.open(ctx, name) },
+	}
+
+	ctx.Context.PevalString(fsModuleJS)
+	obj := ctx.PushObject()
+	for key, fn := range functions {
+		fn := fn
+		ctx.pushNativeFunction(func(*duktape.Context) int {
+			ctx.SetTop(2)
+			name, err := fsPath(ctx.SafeToString(0))
+			if err == nil {
+				err = fn(name)
+			}
+
+			if err != nil {
+				ctx.pushError(err)
+			}
+
+			return 1
+		})
+
+		ctx.PutPropString(obj, key)
+	}
+
+	ctx.Call(1)
+}
+
+// fsPath returns the path relative to the root of the given one, the paths
+// pointing outside the root are invalid.
+func fsPath(name string) (string, error) {
+	clean := path.Clean(strings.TrimLeft(name, "/"))
+	if !fs.ValidPath(clean) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return clean, nil
+}
+
+// root opens the root directory, the returned function closes it.
+func (f *FileSystem) root() (*os.Root, func(), error) {
+	root, err := os.OpenRoot(f.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, func() { root.Close() }, nil
+}
+
+// readFS returns the fs.FS to read from, the returned function closes it.
+func (f *FileSystem) readFS() (fs.FS, func(), error) {
+	if f.Dir == "" {
+		return f.FS, func() {}, nil
+	}
+
+	root, done, err := f.root()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root.FS(), done, nil
+}
+
+// writeRoot returns the root to write to, failing if is read-only.
+func (f *FileSystem) writeRoot() (*os.Root, func(), error) {
+	if f.ReadOnly || f.Dir == "" {
+		return nil, nil, ErrReadOnly
+	}
+
+	return f.root()
+}
+
+func (f *FileSystem) readFile(ctx *Context, name string) error {
+	fsys, done, err := f.readFS()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(ctx.SafeToString(1)) {
+	case "utf8", "utf-8":
+		ctx.PushString(string(content))
+	default:
+		ctx.PushBytes(content)
+	}
+
+	return nil
+}
+
+func (f *FileSystem) writeFile(ctx *Context, name string) error {
+	root, done, err := f.writeRoot()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+	if ctx.IsUndefined(1) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	data, ok := ctx.getBuffer(1)
+	if !ok {
+		data = []byte(ctx.SafeToString(1))
+	}
+
+	if err := root.WriteFile(name, data, 0666); err != nil {
+		return err
+	}
+
+	ctx.PushUndefined()
+	return nil
+}
+
+func (f *FileSystem) readDir(ctx *Context, name string) error {
+	fsys, done, err := f.readFS()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	arr := ctx.PushArray()
+	for i, e := range entries {
+		ctx.PushString(e.Name())
+		ctx.PutPropIndex(arr, uint(i))
+	}
+
+	return nil
+}
+
+func (f *FileSystem) stat(ctx *Context, name string) error {
+	fsys, done, err := f.readFS()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	obj := ctx.PushObject()
+	ctx.PushString(fi.Name())
+	ctx.PutPropString(obj, "name")
+	ctx.PushNumber(float64(fi.Size()))
+	ctx.PutPropString(obj, "size")
+	ctx.PushUint(uint(fi.Mode().Perm()))
+	ctx.PutPropString(obj, "mode")
+	ctx.PushGlobalObject()
+	ctx.GetPropString(-1, "Date")
+	ctx.PushNumber(float64(fi.ModTime().UnixMilli()))
+	ctx.New(1)
+	ctx.Remove(-2)
+	ctx.PutPropString(obj, "modTime")
+	ctx.PushBoolean(fi.IsDir())
+	ctx.PutPropString(obj, "isDir")
+
+	return nil
+}
+
+func (f *FileSystem) mkdir(ctx *Context, name string) error {
+	root, done, err := f.writeRoot()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+	if ctx.ToBoolean(1) {
+		err = root.MkdirAll(name, 0777)
+	} else {
+		err = root.Mkdir(name, 0777)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ctx.PushUndefined()
+	return nil
+}
+
+func (f *FileSystem) remove(ctx *Context, name string) error {
+	root, done, err := f.writeRoot()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+	if ctx.ToBoolean(1) {
+		err = root.RemoveAll(name)
+	} else {
+		err = root.Remove(name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ctx.PushUndefined()
+	return nil
+}
+
+var fsOpenFlags = map[string]int{
+	"r":  os.O_RDONLY,
+	"r+": os.O_RDWR,
+	"w":  os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+	"w+": os.O_RDWR | os.O_CREATE | os.O_TRUNC,
+	"a":  os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+	"a+": os.O_RDWR | os.O_CREATE | os.O_APPEND,
+}
+
+func (f *FileSystem) open(ctx *Context, name string) error {
+	flags := "r"
+	if ctx.IsString(1) {
+		flags = ctx.GetString(1)
+	}
+
+	flag, ok := fsOpenFlags[flags]
+	if !ok {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	var fi *file
+	if flag == os.O_RDONLY {
+		fsys, done, err := f.readFS()
+		if err != nil {
+			return err
+		}
+
+		defer done()
+		r, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+
+		fi = newFile(r)
+	} else {
+		root, done, err := f.writeRoot()
+		if err != nil {
+			return err
+		}
+
+		defer done()
+		w, err := root.OpenFile(name, flag, 0666)
+		if err != nil {
+			return err
+		}
+
+		fi = newFile(w)
+	}
+
+	ctx.pushClosingProxy(fi)
+	return nil
+}
+
+// file is the stream returned by open, only the Read, Write and Close methods
+// of the underlying file are exposed.
+type file struct {
+	f fs.File
+}
+
+func newFile(f fs.File) *file {
+	return &file{f: f}
+}
+
+// Read follows the io.Reader interface.
+func (f *file) Read(p []byte) (int, error) {
+	return f.f.Read(p)
+}
+
+// Write follows the io.Writer interface, failing if the file is read-only.
+func (f *file) Write(p []byte) (int, error) {
+	w, ok := f.f.(io.Writer)
+	if !ok {
+		return 0, ErrReadOnly
+	}
+
+	return w.Write(p)
+}
+
+// Close follows the io.Closer interface.
+func (f *file) Close() error {
+	return f.f.Close()
+}
+
+const fsModuleJS = `(function (impl) {
+	var fs = {};
+	Object.keys(impl).forEach(function (name) {
+		fs[name] = function () {
+			var result = impl[name].apply(null, arguments);
+			if (result instanceof Error) {
+				throw result;
+			}
+
+			return result;
+		};
+	});
+
+	return fs;
+})`
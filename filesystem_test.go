@@ -0,0 +1,199 @@
+package candyjs
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) newFileSystemContext(c *C, opts Options) *Context {
+	ctx := NewContextWithOptions(opts)
+	c.Assert(ctx.PevalString(`
+		var fs = CandyJS.require('fs');
+		function error(fn) {
+			try {
+				fn();
+			} catch (e) {
+				return e.message;
+			}
+		}
+	`), IsNil)
+
+	return ctx
+}
+
+func (s *CandySuite) TestFileSystem(c *C) {
+	dir := c.MkDir()
+	ctx := s.newFileSystemContext(c, Options{FileSystem: &FileSystem{Dir: dir}})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`
+		fs.mkdir('foo/bar', true);
+		fs.writeFile('/foo/bar/qux.txt', 'qux');
+		fs.writeFile('foo/data', new Uint8Array([0, 255]));
+
+		var data = fs.readFile('foo/data');
+		var stat = fs.stat('foo/bar/qux.txt');
+		[
+			fs.readFile('./foo/bar/qux.txt', 'utf8'),
+			data.length, data[1],
+			fs.readDir('foo').join(' '),
+			stat.name, stat.size, stat.isDir, stat.modTime instanceof Date,
+			fs.stat('foo').isDir
+		].join()
+	`), IsNil)
+
+	c.Assert(ctx.GetString(-1), Equals, "qux,2,255,bar data,qux.txt,3,false,true,true")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "foo", "bar", "qux.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "qux")
+
+	c.Assert(ctx.PevalString(`
+		fs.remove('foo/data');
+		fs.remove('foo', true);
+		fs.readDir('/').length
+	`), IsNil)
+	c.Assert(ctx.GetInt(-1), Equals, 0)
+}
+
+func (s *CandySuite) TestFileSystem_Open(c *C) {
+	ctx := s.newFileSystemContext(c, Options{FileSystem: &FileSystem{Dir: c.MkDir()}})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`
+		var w = fs.open('foo.txt', 'w');
+		w.write('foo\n');
+		w.close();
+
+		var a = fs.open('foo.txt', 'a');
+		a.write(new Uint8Array([98, 97, 114]));
+		a.close();
+
+		var r = fs.open('foo.txt'), lines = [], line;
+		while ((line = r.readLine()) !== null) {
+			lines.push(line);
+		}
+
+		r.close();
+		[lines.join(), error(function () { r.chmod(0777); }) !== undefined].join()
+	`), IsNil)
+
+	c.Assert(ctx.GetString(-1), Equals, "foo,bar,true")
+}
+
+func (s *CandySuite) TestFileSystem_Traversal(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(dir, "root"), 0777), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "secret"), []byte("foo"), 0666), IsNil)
+	c.Assert(os.Symlink(filepath.Join(dir, "secret"), filepath.Join(dir, "root", "link")), IsNil)
+
+	ctx := s.newFileSystemContext(c, Options{FileSystem: &FileSystem{Dir: filepath.Join(dir, "root")}})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`[
+		error(function () { fs.readFile('../secret'); }),
+		error(function () { fs.readFile('foo/../../secret'); }),
+		error(function () { fs.writeFile('../foo', 'bar'); }),
+		error(function () { fs.readFile('link'); }) !== undefined
+	].join('|')`), IsNil)
+
+	c.Assert(ctx.GetString(-1), Equals, ""+
+		"open ../secret: invalid argument|"+
+		"open foo/../../secret: invalid argument|"+
+		"open ../foo: invalid argument|"+
+		"true")
+}
+
+func (s *CandySuite) TestFileSystem_ReadOnly(c *C) {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "foo"), []byte("bar"), 0666), IsNil)
+
+	ctx := s.newFileSystemContext(c, Options{FileSystem: &FileSystem{Dir: dir, ReadOnly: true}})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`[
+		fs.readFile('foo', 'utf8'),
+		error(function () { fs.writeFile('foo', 'qux'); }),
+		error(function () { fs.mkdir('qux'); }),
+		error(function () { fs.remove('foo'); }),
+		error(function () { fs.open('foo', 'w'); }),
+		error(function () { fs.open('foo').write('qux'); }) !== undefined
+	].join('|')`), IsNil)
+
+	c.Assert(ctx.GetString(-1), Equals, "bar|"+
+		"read-only file system|read-only file system|"+
+		"read-only file system|read-only file system|true")
+}
+
+func (s *CandySuite) TestFileSystem_FS(c *C) {
+	fsys := fstest.MapFS{"foo/bar.txt": {Data: []byte("qux")}}
+	ctx := s.newFileSystemContext(c, Options{FS: fsys, FileSystem: &FileSystem{FS: fsys}})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`[
+		fs.readFile('foo/bar.txt', 'utf8'),
+		fs.open('foo/bar.txt').read().length,
+		require('fs') === fs,
+		error(function () { fs.writeFile('foo', 'qux'); })
+	].join('|')`), IsNil)
+
+	c.Assert(ctx.GetString(-1), Equals, "qux|3|true|read-only file system")
+}
+
+type recorderFS struct {
+	fstest.MapFS
+	files []*recorderFile
+}
+
+func (r *recorderFS) Open(name string) (fs.File, error) {
+	f, err := r.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &recorderFile{File: f}
+	r.files = append(r.files, file)
+	return file, nil
+}
+
+type recorderFile struct {
+	fs.File
+	closed bool
+}
+
+func (f *recorderFile) Close() error {
+	f.closed = true
+	return f.File.Close()
+}
+
+func (s *CandySuite) TestFileSystem_CloseUnclosed(c *C) {
+	fsys := &recorderFS{MapFS: fstest.MapFS{"foo.txt": {Data: []byte("foo")}}}
+	ctx := s.newFileSystemContext(c, Options{FileSystem: &FileSystem{FS: fsys}})
+
+	c.Assert(ctx.PevalString(`
+		fs.open('foo.txt');
+		fs.open('foo.txt').close();
+		Duktape.gc();
+	`), IsNil)
+
+	c.Assert(fsys.files, HasLen, 2)
+	ctx.releaseStorage()
+	c.Assert(fsys.files[0].closed, Equals, true)
+	c.Assert(fsys.files[1].closed, Equals, true)
+
+	c.Assert(ctx.PevalString(`var kept = fs.open('foo.txt')`), IsNil)
+	ctx.releaseStorage()
+	c.Assert(fsys.files[2].closed, Equals, false)
+
+	ctx.DestroyHeap()
+	c.Assert(fsys.files[2].closed, Equals, true)
+}
+
+func (s *CandySuite) TestFileSystem_Disabled(c *C) {
+	c.Assert(s.ctx.PevalString(`CandyJS.require('fs')`), NotNil)
+}
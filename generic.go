@@ -0,0 +1,154 @@
+package candyjs
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// PushGlobalGenericFunction like PushGenericFunction but pushed to the global
+// object
+func (ctx *Context) PushGlobalGenericFunction(name string, instances ...interface{}) int {
+	ctx.PushGlobalObject()
+	idx := ctx.PushGenericFunction(instances...)
+	ctx.PutPropString(-2, name)
+	ctx.Pop()
+
+	return idx
+}
+
+// PushGenericFunction push a function calling one of the given instantiations
+// of a generic function, since the generic functions cannot be used without
+// instantiate them. The first instantiation accepting the arguments of the
+// call is used, by example:
+//	ctx.PushGlobalGenericFunction("contains",
+//		slices.Contains[[]string, string],
+//		slices.Contains[[]int, int],
+//	)
+//
+//	ctx.PevalString(`contains(['foo', 'bar'], 'foo') && contains([1, 2], 2)`)
+//
+// The values are matched by its JavaScript type, the arrays by its first
+// element and the proxified values by its Go type. The instantiations follow
+// the same rules as the functions pushed with PushGoFunction, if none of them
+// accepts the arguments a TypeError is thrown.
+func (ctx *Context) PushGenericFunction(instances ...interface{}) int {
+	g := genericFunction(instances)
+
+	fns := make([]reflect.Value, len(instances))
+	infos := make([]*funcInfo, len(instances))
+	for i, f := range instances {
+		fns[i] = reflect.ValueOf(f)
+		infos[i] = types.get(fns[i].Type()).fn
+	}
+
+	idx := ctx.pushNativeFunction(func(*duktape.Context) int {
+		for i, info := range infos {
+			if ctx.matchArgs(info) {
+				return ctx.callFunction(fns[i], info, ctx.getFunctionArgs(info))
+			}
+		}
+
+		return duktape.ErrRetType
+	})
+
+	ctx.putGoFunctionRef(idx, g)
+	return idx
+}
+
+// genericFunction is stored as reference of the functions pushed by
+// PushGenericFunction.
+type genericFunction []interface{}
+
+func (g genericFunction) String() string {
+	var types []string
+	for _, f := range g {
+		types = append(types, reflect.TypeOf(f).String())
+	}
+
+	return strings.Join(types, " | ")
+}
+
+func (ctx *Context) matchArgs(f *funcInfo) bool {
+	argc := ctx.GetTop()
+	if argc > len(f.in) && !f.isVariadic {
+		return false
+	}
+
+	for i := 0; i < argc; i++ {
+		if !ctx.matchValue(i, f.argType(i)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (ctx *Context) matchValue(index int, t reflect.Type) bool {
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return true
+	}
+
+	if proxy := ctx.getProxy(index); proxy != nil {
+		pt := reflect.TypeOf(proxy)
+		return pt.AssignableTo(t) || (pt.Kind() == reflect.Ptr && pt.Elem() == t)
+	}
+
+	switch {
+	case ctx.IsNullOrUndefined(index):
+		return true
+	case ctx.IsPointer(index):
+		return t.Kind() == reflect.Func
+	case ctx.IsBoolean(index):
+		return t.Kind() == reflect.Bool
+	case ctx.IsNumber(index):
+		return isNumberKind(t.Kind())
+	case ctx.IsString(index):
+		return t.Kind() == reflect.String || isBytes(t)
+	case ctx.IsBuffer(index):
+		return isBytes(t)
+	case ctx.IsArray(index):
+		return ctx.matchArray(index, t)
+	case ctx.IsFunction(index):
+		return false
+	case ctx.IsObject(index):
+		switch t.Kind() {
+		case reflect.Ptr:
+			return t.Elem().Kind() == reflect.Struct
+		case reflect.Struct, reflect.Map:
+			return true
+		case reflect.Slice:
+			return isBytes(t) && ctx.isBuffer(index)
+		}
+	}
+
+	return false
+}
+
+func (ctx *Context) matchArray(index int, t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+
+	if ctx.GetLength(index) == 0 {
+		return true
+	}
+
+	index = ctx.NormalizeIndex(index)
+	ctx.GetPropIndex(index, 0)
+	defer ctx.Pop()
+
+	return ctx.matchValue(-1, t.Elem())
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
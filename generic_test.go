@@ -0,0 +1,62 @@
+package candyjs
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func contains[S ~[]E, E comparable](s S, v E) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *CandySuite) TestPushGlobalGenericFunction(c *C) {
+	s.ctx.PushGlobalGenericFunction("contains",
+		contains[[]string, string],
+		contains[[]int, int],
+	)
+
+	c.Assert(s.ctx.PevalString(`
+		store([
+			contains(['foo', 'bar'], 'bar'),
+			contains([1, 2], 2),
+			contains([1, 2], 3)
+		])
+	`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{true, true, false})
+}
+
+func (s *CandySuite) TestPushGlobalGenericFunction_NoMatch(c *C) {
+	s.ctx.PushGlobalGenericFunction("contains", contains[[]int, int])
+
+	c.Assert(s.ctx.PevalString(`
+		try { contains(['foo'], 'foo') } catch(err) { store(err.name) }
+	`), IsNil)
+
+	c.Assert(s.stored, Equals, "TypeError")
+}
+
+func (s *CandySuite) TestPushGlobalGenericFunction_Proxy(c *C) {
+	s.ctx.PushGlobalGenericFunction("title",
+		func(m *MyStruct) string { return "struct" },
+		strings.ToUpper,
+	)
+
+	s.ctx.PushGlobalProxy("obj", &MyStruct{})
+	c.Assert(s.ctx.PevalString(`store([title(obj), title('foo')])`), IsNil)
+	c.Assert(s.stored, DeepEquals, []interface{}{"struct", "FOO"})
+}
+
+func (s *CandySuite) TestInspect_GenericFunction(c *C) {
+	s.ctx.PushGenericFunction(contains[[]string, string], contains[[]int, int])
+	c.Assert(s.ctx.Inspect(-1), Equals,
+		"[Go Function: func([]string, string) bool | func([]int, int) bool]",
+	)
+}
@@ -0,0 +1,20 @@
+module github.com/mcuadros/go-candyjs
+
+go 1.25.0
+
+require (
+	github.com/evanw/esbuild v0.28.1
+	github.com/jessevdk/go-flags v1.6.1
+	github.com/olebedev/go-duktape v0.0.0-20210326210528-650f7c854440
+	github.com/peterh/liner v1.2.2
+	golang.org/x/tools v0.47.0
+	gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/mattn/go-runewidth v0.0.3 // indirect
+	golang.org/x/mod v0.37.0 // indirect
+	golang.org/x/sync v0.21.0 // indirect
+	golang.org/x/sys v0.46.0 // indirect
+)
@@ -0,0 +1,276 @@
+package candyjs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrResponseFinished is returned writing to a response already ended, or
+// after the timeout of the handler.
+var ErrResponseFinished = errors.New("response already finished")
+
+// HTTPHandler is a http.Handler calling a JavaScript function on every
+// request, see Handler.
+type HTTPHandler struct {
+	// Pool provides the Contexts where the function is called.
+	Pool *Pool
+	// Name is the name of the global function handling the requests.
+	Name string
+	// Timeout is the maximum duration of a request, when is reached the
+	// script is interrupted at its next call to Go, the Context discarded
+	// and a 500 response sent, if nothing was written yet. Its slot in the
+	// Pool is released, so the next requests are served meanwhile. Zero
+	// means no timeout.
+	Timeout time.Duration
+	// ErrorLog logs the errors of the function, if nil the standard logger
+	// of the log package is used.
+	ErrorLog *log.Logger
+}
+
+// Handler returns a HTTPHandler calling the global function with the given
+// name, usually defined by the setup of the Pool. The function is called with
+// a request and a response similar to the ones of Node.js:
+//  - request: an object with the method, url, path, query, headers, host,
+//    remoteAddr and body, being the body a stream
+//  - response: with the statusCode property and the setHeader, getHeader,
+//    writeHead, write and end methods, a request body can be piped to it
+//
+// The response is ended when the function returns. If the function throws an
+// error, returns a rejected Promise-like or panics, a 500 response is sent, if
+// nothing was written yet, and the Context is discarded.
+func Handler(pool *Pool, name string) *HTTPHandler {
+	return &HTTPHandler{Pool: pool, Name: name}
+}
+
+// ServeHTTP follows the http.Handler interface.
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, h.Timeout)
+		defer cancel()
+	}
+
+	res := &httpResponse{StatusCode: http.StatusOK, w: w}
+	err := h.Pool.Do(c, func(ctx *Context) error {
+		return ctx.serveHTTP(h.Name, r, res)
+	})
+
+	if err != nil {
+		h.logf("candyjs: %s %s: %s", r.Method, r.URL, err)
+		res.abort()
+	}
+}
+
+func (h *HTTPHandler) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+		return
+	}
+
+	log.Printf(format, args...)
+}
+
+// serveHTTP calls the global function with the given name with the request
+// and the response, ending the response when the function returns.
+func (ctx *Context) serveHTTP(name string, r *http.Request, res *httpResponse) error {
+	ctx.pushServeHTTP()
+	ctx.PushGlobalObject()
+	ctx.GetPropString(-1, name)
+	ctx.Remove(-2)
+	if !ctx.IsFunction(-1) {
+		return fmt.Errorf("%w: %s", ErrUndefinedProperty, name)
+	}
+
+	ctx.pushHTTPRequest(r)
+	ctx.PushProxy(res)
+	if ctx.Pcall(3) != 0 {
+		return ctx.getError(-1)
+	}
+
+	res.finish()
+	return nil
+}
+
+// pushServeHTTP pushes the function calling the handlers, stored on the
+// global stash the first time is used, so the globals are not modified.
+func (ctx *Context) pushServeHTTP() {
+	ctx.PushGlobalStash()
+	ctx.GetPropString(-1, "serveHTTP")
+	if !ctx.IsFunction(-1) {
+		ctx.Pop()
+		ctx.Context.PevalString(serveHTTPJS)
+		ctx.Dup(-1)
+		ctx.PutPropString(-3, "serveHTTP")
+	}
+
+	ctx.Remove(-2)
+}
+
+// pushHTTPRequest pushes the request object given to the handlers, the query
+// contains the first value of every parameter.
+func (ctx *Context) pushHTTPRequest(r *http.Request) {
+	obj := ctx.PushObject()
+	ctx.PushString(r.Method)
+	ctx.PutPropString(obj, "method")
+	ctx.PushString(r.URL.RequestURI())
+	ctx.PutPropString(obj, "url")
+	ctx.PushString(r.URL.Path)
+	ctx.PutPropString(obj, "path")
+	ctx.PushString(r.Host)
+	ctx.PutPropString(obj, "host")
+	ctx.PushString(r.RemoteAddr)
+	ctx.PutPropString(obj, "remoteAddr")
+
+	ctx.PushObject()
+	for name, values := range r.URL.Query() {
+		ctx.PushString(values[0])
+		ctx.PutPropString(-2, name)
+	}
+
+	ctx.PutPropString(obj, "query")
+	ctx.pushHeaders(r.Header)
+	ctx.PutPropString(obj, "headers")
+	ctx.PushProxy(r.Body)
+	ctx.PutPropString(obj, "body")
+}
+
+const serveHTTPJS = `(function (fn, req, res) {
+	var result = fn(req, res), error, rejected = false;
+	if (result && typeof result.then === 'function') {
+		result.then(null, function (err) {
+			error = err;
+			rejected = true;
+		});
+	}
+
+	if (rejected) {
+		throw error;
+	}
+})`
+
+// httpResponse is the response given to the handlers, the writes done after
+// the response is finished, like after a timeout, fail.
+type httpResponse struct {
+	// StatusCode is the status sent with the headers, 200 by default
+	StatusCode int
+
+	w           http.ResponseWriter
+	wroteHeader bool
+	finished    bool
+	m           sync.Mutex
+}
+
+// SetHeader sets a header, replacing any previous value.
+func (r *httpResponse) SetHeader(name, value string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.finished {
+		return ErrResponseFinished
+	}
+
+	r.w.Header().Set(name, value)
+	return nil
+}
+
+// GetHeader returns the value of a header.
+func (r *httpResponse) GetHeader(name string) (string, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.finished {
+		return "", ErrResponseFinished
+	}
+
+	return r.w.Header().Get(name), nil
+}
+
+// WriteHead writes the status and the headers, the given headers are added
+// to the ones already set.
+func (r *httpResponse) WriteHead(status int, headers map[string]string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.finished {
+		return ErrResponseFinished
+	}
+
+	for name, value := range headers {
+		r.w.Header().Set(name, value)
+	}
+
+	r.StatusCode = status
+	r.writeHeader()
+	return nil
+}
+
+// Write writes data to the body, a string or a buffer.
+func (r *httpResponse) Write(data []byte) (int, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.finished {
+		return 0, ErrResponseFinished
+	}
+
+	r.writeHeader()
+	return r.w.Write(data)
+}
+
+// End writes the given data, if any, and finishes the response.
+func (r *httpResponse) End(data []byte) error {
+	if len(data) != 0 {
+		if _, err := r.Write(data); err != nil {
+			return err
+		}
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.finished {
+		return ErrResponseFinished
+	}
+
+	r.writeHeader()
+	r.finished = true
+	return nil
+}
+
+func (r *httpResponse) writeHeader() {
+	if !r.wroteHeader {
+		r.w.WriteHeader(r.StatusCode)
+		r.wroteHeader = true
+	}
+}
+
+// finish ends the response if the handler did not.
+func (r *httpResponse) finish() {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if !r.finished {
+		r.writeHeader()
+		r.finished = true
+	}
+}
+
+// abort sends a 500 response, if nothing was written yet, and finishes the
+// response.
+func (r *httpResponse) abort() {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if !r.wroteHeader && !r.finished {
+		code := http.StatusInternalServerError
+		http.Error(r.w, http.StatusText(code), code)
+	}
+
+	r.finished = true
+}
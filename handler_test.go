@@ -0,0 +1,161 @@
+package candyjs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) newHandlerPool(c *C, src string) *Pool {
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error { return ctx.PevalString(src) },
+		Max:   1,
+	})
+
+	c.Assert(err, IsNil)
+	return p
+}
+
+func (s *CandySuite) TestHandler(c *C) {
+	p := s.newHandlerPool(c, `
+		function handle(req, res) {
+			res.setHeader('Content-Type', 'text/plain');
+			res.writeHead(201, {'X-Path': req.path});
+			res.write(req.method + ' ' + req.url + ' ' + req.query.foo + ' ' + req.headers['x-foo'] + ' ');
+			req.body.pipe(res);
+		}
+	`)
+	defer p.Close()
+
+	r := httptest.NewRequest("POST", "/qux?foo=bar", strings.NewReader("body"))
+	r.Header.Set("X-Foo", "baz")
+	w := httptest.NewRecorder()
+	Handler(p, "handle").ServeHTTP(w, r)
+
+	c.Assert(w.Code, Equals, http.StatusCreated)
+	c.Assert(w.Header().Get("Content-Type"), Equals, "text/plain")
+	c.Assert(w.Header().Get("X-Path"), Equals, "/qux")
+	c.Assert(w.Body.String(), Equals, "POST /qux?foo=bar bar baz body")
+}
+
+func (s *CandySuite) TestHandler_StatusCode(c *C) {
+	p := s.newHandlerPool(c, `
+		function handle(req, res) {
+			res.statusCode = 404;
+			res.end(new Uint8Array([102, 111, 111]));
+		}
+	`)
+	defer p.Close()
+
+	w := httptest.NewRecorder()
+	Handler(p, "handle").ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+	c.Assert(w.Body.String(), Equals, "foo")
+}
+
+func (s *CandySuite) TestHandler_Error(c *C) {
+	p := s.newHandlerPool(c, `
+		function throws(req, res) {
+			throw new Error('foo');
+		}
+
+		function rejects(req, res) {
+			return {then: function (resolve, reject) { reject(new Error('bar')); }};
+		}
+	`)
+	defer p.Close()
+
+	logs := bytes.NewBuffer(nil)
+	for _, name := range []string{"throws", "rejects", "missing"} {
+		h := Handler(p, name)
+		h.ErrorLog = log.New(logs, "", 0)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		c.Assert(w.Code, Equals, http.StatusInternalServerError)
+	}
+
+	c.Assert(logs.String(), Equals, ""+
+		"candyjs: GET /: Error: foo (eval:3)\n"+
+		"candyjs: GET /: Error: bar (eval:7)\n"+
+		"candyjs: GET /: undefined property: missing\n")
+}
+
+func (s *CandySuite) TestHandler_Timeout(c *C) {
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error {
+			ctx.PushGlobalGoFunction("sleep", func() { time.Sleep(20 * time.Millisecond) })
+			return ctx.PevalString(`
+				function handle(req, res) {
+					if (req.path === '/loop') {
+						while (true) {
+							sleep();
+						}
+					}
+
+					sleep();
+					res.end('foo');
+				}
+			`)
+		},
+		Max: 1,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	h := Handler(p, "handle")
+	h.Timeout = 10 * time.Millisecond
+	h.ErrorLog = log.New(ioutil.Discard, "", 0)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/loop", nil))
+	c.Assert(w.Code, Equals, http.StatusInternalServerError)
+
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(w.Body.String(), Equals, "Internal Server Error\n")
+
+	h.Timeout = time.Second
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(w.Body.String(), Equals, "foo")
+}
+
+func (s *CandySuite) TestHandler_ReleaseStorage(c *C) {
+	p := s.newHandlerPool(c, `
+		function handle(req, res) {
+			req.body.pipe(res);
+		}
+	`)
+	defer p.Close()
+
+	serve := func() {
+		w := httptest.NewRecorder()
+		Handler(p, "handle").ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("foo")))
+		c.Assert(w.Body.String(), Equals, "foo")
+	}
+
+	serve()
+	ctx, err := p.Get()
+	c.Assert(err, IsNil)
+	size := ctx.storage.len()
+	p.Put(ctx)
+
+	for i := 0; i < 100; i++ {
+		serve()
+	}
+
+	other, err := p.Get()
+	c.Assert(err, IsNil)
+	c.Assert(other, Equals, ctx)
+	c.Assert(ctx.storage.len(), Equals, size)
+	p.Put(ctx)
+}
@@ -0,0 +1,287 @@
+package candyjs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/olebedev/go-duktape"
+)
+
+const (
+	inspectMaxDepth = 3
+	// inspectMaxBytes is the number of bytes shown of the buffers
+	inspectMaxBytes = 50
+)
+
+// Inspect returns a human readable representation of the value at the given
+// index, like the util.inspect function of Node.js. The proxified Go values are
+// represented with its type, fields and methods.
+func (ctx *Context) Inspect(index int) string {
+	i := &inspector{ctx: ctx, seen: make(map[unsafe.Pointer]bool, 0)}
+	return i.value(ctx.NormalizeIndex(index), 0)
+}
+
+type inspector struct {
+	ctx  *Context
+	seen map[unsafe.Pointer]bool
+}
+
+func (i *inspector) value(index int, depth int) string {
+	ctx := i.ctx
+
+	switch {
+	case ctx.IsUndefined(index):
+		return "undefined"
+	case ctx.IsNull(index):
+		return "null"
+	case ctx.IsString(index):
+		return strconv.Quote(jsToUTF8(ctx.GetString(index)))
+	case ctx.IsBuffer(index):
+		return i.buffer(index)
+	case !ctx.IsObject(index):
+		return ctx.SafeToString(index)
+	}
+
+	if proxy := ctx.getProxy(index); proxy != nil {
+		return inspectGo(reflect.ValueOf(proxy), depth)
+	}
+
+	ptr := ctx.GetHeapptr(index)
+	if i.seen[ptr] {
+		return "[Circular]"
+	}
+
+	i.seen[ptr] = true
+	defer delete(i.seen, ptr)
+
+	switch {
+	case ctx.IsFunction(index):
+		return i.function(index)
+	case ctx.IsError(index):
+		return i.error(index)
+	case ctx.IsArray(index):
+		if depth >= inspectMaxDepth {
+			return "[Array]"
+		}
+
+		return i.array(index, depth)
+	default:
+		if depth >= inspectMaxDepth {
+			return "[Object]"
+		}
+
+		return i.object(index, depth)
+	}
+}
+
+func (i *inspector) function(index int) string {
+	if f := i.ctx.getGoFunctionRef(index); f != nil {
+		switch f := f.(type) {
+		case typeConstructor:
+			return fmt.Sprintf("[Go Type: %s]", f.t)
+		case genericFunction:
+			return fmt.Sprintf("[Go Function: %s]", f)
+		}
+
+		return fmt.Sprintf("[Go Function: %s]", reflect.TypeOf(f))
+	}
+
+	i.ctx.GetPropString(index, "name")
+	defer i.ctx.Pop()
+
+	name := i.ctx.SafeToString(-1)
+	if !i.ctx.IsString(-1) || name == "" {
+		return "[Function]"
+	}
+
+	return fmt.Sprintf("[Function: %s]", name)
+}
+
+func (i *inspector) error(index int) string {
+	i.ctx.GetPropString(index, "stack")
+	defer i.ctx.Pop()
+
+	if i.ctx.IsString(-1) {
+		return i.ctx.GetString(-1)
+	}
+
+	return i.ctx.SafeToString(index)
+}
+
+func (i *inspector) buffer(index int) string {
+	b, _ := i.ctx.getBuffer(index)
+	return inspectBytes(b)
+}
+
+// inspectBytes returns the representation of a buffer, as the Uint8Array
+// received by JavaScript.
+func inspectBytes(b []byte) string {
+	var items []string
+	for n := 0; n < len(b) && n < inspectMaxBytes; n++ {
+		items = append(items, strconv.Itoa(int(b[n])))
+	}
+
+	if len(b) > inspectMaxBytes {
+		items = append(items, fmt.Sprintf("... %d more bytes", len(b)-inspectMaxBytes))
+	}
+
+	if len(items) == 0 {
+		return "Uint8Array(0) []"
+	}
+
+	return fmt.Sprintf("Uint8Array(%d) [ %s ]", len(b), strings.Join(items, ", "))
+}
+
+func (i *inspector) array(index int, depth int) string {
+	var items []string
+
+	length := i.ctx.GetLength(index)
+	for n := 0; n < length; n++ {
+		i.ctx.GetPropIndex(index, uint(n))
+		items = append(items, i.value(i.ctx.NormalizeIndex(-1), depth+1))
+		i.ctx.Pop()
+	}
+
+	if len(items) == 0 {
+		return "[]"
+	}
+
+	return "[ " + strings.Join(items, ", ") + " ]"
+}
+
+func (i *inspector) object(index int, depth int) string {
+	var items []string
+
+	i.ctx.Enum(index, duktape.EnumOwnPropertiesOnly)
+	for i.ctx.Next(-1, true) {
+		key := i.ctx.SafeToString(-2)
+		value := i.value(i.ctx.NormalizeIndex(-1), depth+1)
+		items = append(items, key+": "+value)
+		i.ctx.Pop2()
+	}
+
+	i.ctx.Pop()
+
+	if len(items) == 0 {
+		return "{}"
+	}
+
+	return "{ " + strings.Join(items, ", ") + " }"
+}
+
+func inspectGo(v reflect.Value, depth int) string {
+	if !v.IsValid() {
+		return "null"
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return inspectGo(v.Elem(), depth)
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "null"
+		}
+
+		if v.Elem().Kind() == reflect.Struct {
+			return inspectGoStruct(v, v.Elem(), depth)
+		}
+
+		return inspectGo(v.Elem(), depth)
+	case reflect.Struct:
+		return inspectGoStruct(v, v, depth)
+	case reflect.Func:
+		return fmt.Sprintf("[Go Function: %s]", v.Type())
+	case reflect.Slice, reflect.Array:
+		if isBytes(v.Type()) {
+			return inspectBytes(v.Bytes())
+		}
+
+		if depth >= inspectMaxDepth {
+			return "[Array]"
+		}
+
+		var items []string
+		for n := 0; n < v.Len(); n++ {
+			items = append(items, inspectGo(v.Index(n), depth+1))
+		}
+
+		return "[ " + strings.Join(items, ", ") + " ]"
+	case reflect.Map:
+		if depth >= inspectMaxDepth {
+			return "[Map]"
+		}
+
+		keys := v.MapKeys()
+		sortMapKeys(keys)
+
+		var items []string
+		for _, key := range keys {
+			items = append(items, fmt.Sprintf(
+				"%v: %s", key.Interface(), inspectGo(v.MapIndex(key), depth+1),
+			))
+		}
+
+		return fmt.Sprintf("%s { %s }", v.Type(), strings.Join(items, ", "))
+	}
+
+	if v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+
+		return fmt.Sprint(v.Interface())
+	}
+
+	return v.String()
+}
+
+// sortMapKeys sorts the keys of a map, the numbers by its value and any other
+// by its representation.
+func sortMapKeys(keys []reflect.Value) {
+	sort.Slice(keys, func(a, b int) bool {
+		ka, kb := keys[a], keys[b]
+		switch ka.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return ka.Int() < kb.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return ka.Uint() < kb.Uint()
+		case reflect.Float32, reflect.Float64:
+			return ka.Float() < kb.Float()
+		}
+
+		return fmt.Sprint(ka.Interface()) < fmt.Sprint(kb.Interface())
+	})
+}
+
+func inspectGoStruct(v, s reflect.Value, depth int) string {
+	if depth >= inspectMaxDepth {
+		return fmt.Sprintf("[%s]", v.Type())
+	}
+
+	var items []string
+	t := s.Type()
+	for n := 0; n < t.NumField(); n++ {
+		name := t.Field(n).Name
+		if !isExported(name) {
+			continue
+		}
+
+		items = append(items, nameToJavaScript(name)+": "+inspectGo(s.Field(n), depth+1))
+	}
+
+	for n := 0; n < v.NumMethod(); n++ {
+		items = append(items, nameToJavaScript(v.Type().Method(n).Name)+"()")
+	}
+
+	if len(items) == 0 {
+		return fmt.Sprintf("%s {}", v.Type())
+	}
+
+	return fmt.Sprintf("%s { %s }", v.Type(), strings.Join(items, ", "))
+}
@@ -0,0 +1,72 @@
+package candyjs
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestInspect(c *C) {
+	provider := [][]string{
+		{`undefined`, `undefined`},
+		{`null`, `null`},
+		{`42`, `42`},
+		{`true`, `true`},
+		{`"foo"`, `"foo"`},
+		{`"a😀"`, `"a😀"`},
+		{`[1, "2", [3]]`, `[ 1, "2", [ 3 ] ]`},
+		{`({foo: {bar: 42}, qux: []})`, `{ foo: { bar: 42 }, qux: [] }`},
+		{`({a: {b: {c: {d: 1}}}})`, `{ a: { b: { c: [Object] } } }`},
+		{`(function foo() {})`, `[Function: foo]`},
+		{`a = {}; a.a = a; a`, `{ a: [Circular] }`},
+	}
+
+	for _, p := range provider {
+		c.Assert(s.ctx.PevalString(p[0]), IsNil)
+		c.Assert(s.ctx.Inspect(-1), Equals, p[1], Commentf("%s", p[0]))
+		s.ctx.Pop()
+	}
+}
+
+func (s *CandySuite) TestInspect_Error(c *C) {
+	c.Assert(s.ctx.PevalString(`new TypeError("foo")`), IsNil)
+	c.Assert(s.ctx.Inspect(-1), Matches, "TypeError: foo(\n.*)*")
+}
+
+func (s *CandySuite) TestInspect_Go(c *C) {
+	s.ctx.PushProxy(&inspectStruct{Int: 42, Nested: &inspectStruct{String: "foo"}})
+	c.Assert(s.ctx.Inspect(-1), Equals, ``+
+		`*candyjs.inspectStruct { int: 42, string: "", nested: `+
+		`*candyjs.inspectStruct { int: 0, string: "foo", nested: null, foo() }, foo() }`,
+	)
+
+	s.ctx.PushGoFunction(func(a, b int) int { return a * b })
+	c.Assert(s.ctx.Inspect(-1), Equals, `[Go Function: func(int, int) int]`)
+}
+
+func (s *CandySuite) TestInspect_GoBytesAndMaps(c *C) {
+	s.ctx.PushProxy(&inspectValues{
+		Bytes:  []byte("foo"),
+		Map:    map[int]string{10: "a", 2: "b", 1: "c", 33: "d"},
+		Labels: map[string]int{"foo": 1, "bar": 2, "qux": 3},
+	})
+
+	c.Assert(s.ctx.Inspect(-1), Equals, ``+
+		`*candyjs.inspectValues { bytes: Uint8Array(3) [ 102, 111, 111 ], `+
+		`map: map[int]string { 1: "c", 2: "b", 10: "a", 33: "d" }, `+
+		`labels: map[string]int { bar: 2, foo: 1, qux: 3 } }`,
+	)
+}
+
+type inspectStruct struct {
+	Int     int
+	String  string
+	Nested  *inspectStruct
+	private int
+}
+
+type inspectValues struct {
+	Bytes  []byte
+	Map    map[int]string
+	Labels map[string]int
+}
+
+func (s *inspectStruct) Foo() {}
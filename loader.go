@@ -0,0 +1,223 @@
+package candyjs
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// moduleWrapper is the function wrapping the source of every module, the
+// source starts at the same line, so the line numbers are not altered.
+const moduleWrapper = "function (exports, require, module, __filename, __dirname) {"
+
+// SetModuleDir is a shortcut of SetModuleFS using the given directory as root.
+func (ctx *Context) SetModuleDir(dir string) {
+	ctx.SetModuleFS(os.DirFS(dir))
+}
+
+// SetModuleFS installs a CommonJS module loader reading the modules from the
+// given fs.FS, the global `require` and `CandyJS.require` are replaced by the
+// loader `require`, being relative to the root of fsys. The modules are
+// resolved following the rules used by node.js:
+//  - Names starting by `./`, `../` or `/` are loaded from the file system,
+//    relative to the requiring file or the root respectively. The exact name
+//    is tried first and then adding the `.js` and `.json` extensions.
+//  - Directories are loaded using the `main` file of its package.json, or the
+//    index.js or index.json files.
+//  - Other names are the Go packages registered with RegisterPackagePusher, or
+//    are searched in the `node_modules` directories from the requiring file
+//    up to the root.
+//
+// The modules are evaluated once, being cached by its filename, and on cycles
+// the modules being loaded are returned with its exports partially filled.
+func (ctx *Context) SetModuleFS(fsys fs.FS) {
+	l := &loader{fsys: fsys}
+
+	ctx.Context.PevalString(loaderJS)
+	ctx.PushGoFunction(l.resolve)
+	ctx.pushNativeFunction(func(*duktape.Context) int {
+		l.load(ctx, ctx.GetString(0))
+		return 1
+	})
+
+	ctx.PushGoFunction(ctx.hasPackage)
+
+	ctx.PushGoFunction(func(pckgName string) error {
+		return ctx.pushPackage(pckgName)
+	})
+
+	ctx.Call(4)
+
+	ctx.PushGlobalObject()
+	ctx.GetPropString(-1, "CandyJS")
+	ctx.Dup(-3)
+	ctx.PutPropString(-2, "require")
+	ctx.Pop()
+	ctx.Swap(-2, -1)
+	ctx.PutPropString(-2, "require")
+	ctx.Pop()
+}
+
+type loader struct {
+	fsys fs.FS
+}
+
+// resolve returns the filename of the module required from the given
+// directory, an empty string is returned if cannot be found.
+func (l *loader) resolve(dir, name string) string {
+	var candidates []string
+	switch {
+	case strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../"):
+		candidates = []string{path.Join(dir, name)}
+	case strings.HasPrefix(name, "/"):
+		candidates = []string{path.Clean(name[1:])}
+	default:
+		for {
+			candidates = append(candidates, path.Join(dir, "node_modules", name))
+			if dir == "." {
+				break
+			}
+
+			dir = path.Dir(dir)
+		}
+	}
+
+	for _, c := range candidates {
+		if filename := l.resolveFile(c); filename != "" {
+			return filename
+		}
+
+		if filename := l.resolveDir(c); filename != "" {
+			return filename
+		}
+	}
+
+	return ""
+}
+
+func (l *loader) resolveFile(name string) string {
+	for _, filename := range []string{name, name + ".js", name + ".json"} {
+		if l.isFile(filename) {
+			return filename
+		}
+	}
+
+	return ""
+}
+
+func (l *loader) resolveDir(dir string) string {
+	if content, err := fs.ReadFile(l.fsys, path.Join(dir, "package.json")); err == nil {
+		var pkg struct{ Main string }
+		if json.Unmarshal(content, &pkg) == nil && pkg.Main != "" {
+			main := path.Join(dir, pkg.Main)
+			if filename := l.resolveFile(main); filename != "" {
+				return filename
+			}
+
+			if filename := l.resolveFile(path.Join(main, "index")); filename != "" {
+				return filename
+			}
+		}
+	}
+
+	return l.resolveFile(path.Join(dir, "index"))
+}
+
+func (l *loader) isFile(filename string) bool {
+	if !fs.ValidPath(filename) {
+		return false
+	}
+
+	fi, err := fs.Stat(l.fsys, filename)
+	return err == nil && !fi.IsDir()
+}
+
+// load pushes the content of the given file, the JSON files are pushed as a
+// string and the JavaScript ones as a function, compiled with moduleWrapper.
+// On error, the error is pushed.
+func (l *loader) load(ctx *Context, filename string) {
+	content, err := fs.ReadFile(l.fsys, filename)
+	if err != nil {
+		ctx.pushError(err)
+		return
+	}
+
+	if path.Ext(filename) == ".json" {
+		ctx.PushString(string(content))
+		return
+	}
+
+	src, err := ctx.transformModule(filename, string(content))
+	if err != nil {
+		ctx.pushError(err)
+		return
+	}
+
+	ctx.PushString(filename)
+	ctx.PcompileStringFilename(duktape.CompileFunction, moduleWrapper+src+"\n}")
+}
+
+const loaderJS = `(function (resolve, load, isPackage, requirePackage) {
+	var cache = {};
+
+	function dirname(filename) {
+		var i = filename.lastIndexOf('/');
+		return i === -1 ? '.' : filename.slice(0, i);
+	}
+
+	function evaluate(module) {
+		var fn = load(module.filename);
+		if (fn instanceof Error) {
+			throw fn;
+		}
+
+		if (typeof fn === 'string') {
+			module.exports = JSON.parse(fn);
+			return;
+		}
+
+		var dir = dirname(module.filename);
+		fn.call(module.exports, module.exports, newRequire(dir), module, module.filename, dir);
+	}
+
+	function newRequire(dir) {
+		var require = function (name) {
+			var isPath = /^\.{0,2}\//.test(name);
+			if (!isPath && isPackage(name)) {
+				return requirePackage(name);
+			}
+
+			var filename = resolve(dir, name);
+			if (filename === '') {
+				throw new Error("Cannot find module '" + name + "'");
+			}
+
+			var module = cache[filename];
+			if (module) {
+				return module.exports;
+			}
+
+			module = {id: filename, filename: filename, exports: {}, loaded: false};
+			cache[filename] = module;
+
+			try {
+				evaluate(module);
+			} catch (e) {
+				delete cache[filename];
+				throw e;
+			}
+
+			module.loaded = true;
+			return module.exports;
+		};
+
+		require.cache = cache;
+		return require;
+	}
+
+	return newRequire('.');
+})`
@@ -0,0 +1,94 @@
+package candyjs
+
+import (
+	"testing/fstest"
+
+	. "gopkg.in/check.v1"
+)
+
+var modulesFS = fstest.MapFS{
+	"main.js": {Data: []byte(`
+		var util = require('./lib/util');
+		module.exports = util.double(require('./data').value);
+	`)},
+	"lib/util.js": {Data: []byte(`
+		exports.double = function(x) { return x * 2; };
+		exports.dirname = __dirname;
+	`)},
+	"data.json":                         {Data: []byte(`{"value": 21}`)},
+	"lib/node_modules/qux/package.json": {Data: []byte(`{"main": "lib/qux"}`)},
+	"lib/node_modules/qux/lib/qux.js":   {Data: []byte(`module.exports = 'qux';`)},
+	"lib/uses-qux.js":                   {Data: []byte(`module.exports = require('qux');`)},
+	"node_modules/bar/index.js":         {Data: []byte(`module.exports = require('../../lib/util').dirname;`)},
+	"a.js": {Data: []byte(`
+		exports.done = false;
+		var b = require('./b');
+		exports.b = b.done;
+		exports.done = true;
+	`)},
+	"b.js": {Data: []byte(`
+		var a = require('./a');
+		exports.a = a.done;
+		exports.done = true;
+	`)},
+	"error.js": {Data: []byte(`
+		throw new Error('foo');
+	`)},
+}
+
+func (s *CandySuite) TestSetModuleFS(c *C) {
+	s.ctx.SetModuleFS(modulesFS)
+
+	c.Assert(s.ctx.PevalString(`store(require('./main'))`), IsNil)
+	c.Assert(s.stored, Equals, 42.0)
+}
+
+func (s *CandySuite) TestSetModuleFS_NodeModules(c *C) {
+	s.ctx.SetModuleFS(modulesFS)
+
+	c.Assert(s.ctx.PevalString(`store(require('./lib/uses-qux'))`), IsNil)
+	c.Assert(s.stored, Equals, "qux")
+
+	c.Assert(s.ctx.PevalString(`store(CandyJS.require('bar'))`), IsNil)
+	c.Assert(s.stored, Equals, "lib")
+}
+
+func (s *CandySuite) TestSetModuleFS_Cycle(c *C) {
+	s.ctx.SetModuleFS(modulesFS)
+
+	c.Assert(s.ctx.PevalString(`
+		var a = require('./a');
+		store([a.b, require('./b').a, a === require('./a.js')])
+	`), IsNil)
+	c.Assert(s.stored, DeepEquals, []interface{}{true, false, true})
+}
+
+func (s *CandySuite) TestSetModuleFS_Package(c *C) {
+	RegisterPackagePusher("foo", func(ctx *Context) {
+		ctx.PushString("qux")
+	})
+
+	s.ctx.SetModuleFS(modulesFS)
+
+	c.Assert(s.ctx.PevalString(`store(require('foo'))`), IsNil)
+	c.Assert(s.stored, Equals, "qux")
+}
+
+func (s *CandySuite) TestSetModuleFS_Errors(c *C) {
+	s.ctx.SetModuleFS(modulesFS)
+
+	err := s.ctx.PevalString(`require('./missing')`)
+	c.Assert(err, ErrorMatches, ".*Cannot find module './missing'")
+
+	err = s.ctx.PevalString(`require('../main')`)
+	c.Assert(err, ErrorMatches, ".*Cannot find module '../main'")
+
+	c.Assert(s.ctx.PevalString(`
+		try {
+			require('./error');
+		} catch (e) {
+			store([e.message, e.fileName, e.lineNumber]);
+		}
+	`), IsNil)
+	c.Assert(s.stored, DeepEquals, []interface{}{"foo", "error.js", 2.0})
+}
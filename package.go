@@ -37,13 +37,60 @@ func (ctx *Context) PushGlobalPackage(pckgName, alias string) error {
 	return nil
 }
 
+// hasPackage returns if the given package is one of the Context or a registered
+// one.
+func (ctx *Context) hasPackage(pckgName string) bool {
+	_, ok := ctx.packages[pckgName]
+	if !ok {
+		_, ok = pushers[pckgName]
+	}
+
+	return ok
+}
+
+// packagesStashProp is the property of the global stash holding the objects
+// of the packages already pushed on the Context.
+const packagesStashProp = "candyjsPackages"
+
+// pushPackage push the object of the given package, the PackagePusher is
+// called only once per Context, the following calls push the same object.
 func (ctx *Context) pushPackage(pckgName string) error {
-	f, ok := pushers[pckgName]
+	ctx.pushPackages()
+	if ctx.GetPropString(-1, pckgName) {
+		ctx.Remove(-2)
+		return nil
+	}
+
+	ctx.Pop()
+
+	f, ok := ctx.packages[pckgName]
+	if !ok {
+		f, ok = pushers[pckgName]
+	}
+
 	if !ok {
+		ctx.Pop()
 		return ErrPackageNotFound
 	}
 
 	f(ctx)
+	ctx.Dup(-1)
+	ctx.PutPropString(-3, pckgName)
+	ctx.Remove(-2)
 
 	return nil
 }
+
+// pushPackages pushes the object of the global stash holding the objects of
+// the pushed packages, creating it if is missing.
+func (ctx *Context) pushPackages() {
+	ctx.PushGlobalStash()
+	if !ctx.GetPropString(-1, packagesStashProp) {
+		ctx.Pop()
+		ctx.PushObject()
+		ctx.Dup(-1)
+		ctx.PutPropString(-3, packagesStashProp)
+	}
+
+	ctx.Remove(-2)
+}
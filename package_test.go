@@ -26,3 +26,20 @@ func (s *CandySuite) TestPushGlobalPackage(c *C) {
 func (s *CandySuite) TestPushGlobalPackage_NotFound(c *C) {
 	c.Assert(s.ctx.PushGlobalPackage("qux", "qux"), Equals, ErrPackageNotFound)
 }
+
+func (s *CandySuite) TestPushGlobalPackage_Cached(c *C) {
+	var calls int
+	RegisterPackagePusher("foo", func(ctx *Context) {
+		calls++
+		ctx.PushObject()
+	})
+
+	c.Assert(s.ctx.PevalString(`
+		var foo = CandyJS.require('foo');
+		foo.bar = 42;
+		store(CandyJS.require('foo').bar)
+	`), IsNil)
+
+	c.Assert(s.stored, Equals, 42.0)
+	c.Assert(calls, Equals, 1)
+}
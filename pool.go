@@ -0,0 +1,593 @@
+package candyjs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// ErrPoolClosed is returned when a Context is requested to a closed Pool.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// ErrContextNotTaken is the panic of Put and Discard when the Context was not
+// taken from the Pool, or was already returned.
+var ErrContextNotTaken = errors.New("context not taken from the pool")
+
+// PoolConfig configures a Pool.
+type PoolConfig struct {
+	// Setup is called for every new Context before is handed out, it should
+	// register the packages, types and functions required by the scripts.
+	// If returns an error the Context is discarded.
+	Setup func(ctx *Context) error
+	// Warm is the number of Contexts created when the Pool is created.
+	Warm int
+	// Max is the maximum number of live Contexts, including the ones being
+	// used, Get blocks until a Context is available when the limit is reached.
+	Max int
+	// Options are used to create every Context.
+	Options Options
+}
+
+// Pool is a set of Contexts ready to be used, prepared by a setup function.
+// A Pool is safe for concurrent use, but every Context should only be used by
+// one goroutine at a time.
+type Pool struct {
+	setup   func(ctx *Context) error
+	options Options
+	idle    chan *Context
+	live    chan struct{}
+	closing chan struct{}
+	globals map[*Context]*globalsState
+	taken   map[*Context]bool
+	closed  bool
+	sync.Mutex
+}
+
+// NewPool returns a new Pool with cfg.Warm Contexts already created, Max
+// should be greater than zero.
+func NewPool(cfg PoolConfig) (*Pool, error) {
+	if cfg.Max <= 0 {
+		return nil, fmt.Errorf("invalid pool size %d", cfg.Max)
+	}
+
+	p := &Pool{
+		setup:   cfg.Setup,
+		options: cfg.Options,
+		idle:    make(chan *Context, cfg.Max),
+		live:    make(chan struct{}, cfg.Max),
+		closing: make(chan struct{}),
+		globals: make(map[*Context]*globalsState, 0),
+		taken:   make(map[*Context]bool, 0),
+	}
+
+	for i := 0; i < cfg.Warm && i < cfg.Max; i++ {
+		p.live <- struct{}{}
+		ctx, err := p.newContext()
+		if err != nil {
+			<-p.live
+			p.Close()
+			return nil, err
+		}
+
+		p.idle <- ctx
+	}
+
+	return p, nil
+}
+
+func (p *Pool) newContext() (*Context, error) {
+	ctx := NewContextWithOptions(p.options)
+	if p.setup != nil {
+		if err := p.setup(ctx); err != nil {
+			ctx.DestroyHeap()
+			return nil, err
+		}
+	}
+
+	ctx.SetTop(0)
+
+	p.Lock()
+	p.globals[ctx] = ctx.recordGlobals()
+	p.Unlock()
+
+	return ctx, nil
+}
+
+// Get returns an idle Context or a new one, if the maximum of live Contexts
+// was reached blocks until other Context is returned or discarded.
+func (p *Pool) Get() (*Context, error) {
+	return p.get(nil)
+}
+
+func (p *Pool) get(done <-chan struct{}) (*Context, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+
+	select {
+	case ctx := <-p.idle:
+		return p.take(ctx)
+	default:
+	}
+
+	select {
+	case ctx := <-p.idle:
+		return p.take(ctx)
+	case p.live <- struct{}{}:
+		if p.isClosed() {
+			<-p.live
+			return nil, ErrPoolClosed
+		}
+
+		ctx, err := p.newContext()
+		if err != nil {
+			<-p.live
+			return nil, err
+		}
+
+		return p.take(ctx)
+	case <-p.closing:
+		return nil, ErrPoolClosed
+	case <-done:
+		return nil, context.Canceled
+	}
+}
+
+// take marks the Context as taken, unless the Pool was closed meanwhile, then
+// the Context is destroyed.
+func (p *Pool) take(ctx *Context) (*Context, error) {
+	p.Lock()
+	closed := p.closed
+	if !closed {
+		p.taken[ctx] = true
+	}
+
+	p.Unlock()
+
+	if closed {
+		p.destroy(ctx)
+		return nil, ErrPoolClosed
+	}
+
+	return ctx, nil
+}
+
+// giveBack unmarks the Context as taken, panics with ErrContextNotTaken if
+// was not taken.
+func (p *Pool) giveBack(ctx *Context) {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.taken[ctx] {
+		panic(ErrContextNotTaken)
+	}
+
+	delete(p.taken, ctx)
+}
+
+// Put returns the Context to the Pool. The stack is emptied and the globals
+// created since the setup are removed. The Contexts are discarded when the
+// globals cannot be removed, or when any property of the objects reachable
+// from the global object was modified since the setup, like the globals
+// defined by the setup, their prototypes or the built-in objects, so the
+// changes are never seen by the next user. The values only reachable from
+// closures and the proxied Go values are not checked.
+//
+// The modules required after the setup are also a modification, so they
+// should be required by the setup. The Contexts that pushed ExternalBytes
+// since the setup are discarded too, releasing the bytes.
+//
+// The packages are cached by the Context, so as the globals the packages
+// required since the setup are removed, and the objects of the ones required
+// by the setup are checked.
+//
+// Checking the reachable objects walks the whole heap, taking a few
+// milliseconds with the built-in objects alone, which should be taken into
+// account for short lived uses. Put panics with ErrContextNotTaken if the
+// Context was not taken from the Pool, or was already returned.
+func (p *Pool) Put(ctx *Context) {
+	p.giveBack(ctx)
+	if p.isClosed() || !p.reset(ctx) {
+		p.destroy(ctx)
+		return
+	}
+
+	p.idle <- ctx
+}
+
+func (p *Pool) reset(ctx *Context) bool {
+	p.Lock()
+	globals := p.globals[ctx]
+	p.Unlock()
+
+	ctx.SetTop(0)
+	if len(ctx.externalBytes) > globals.externalBytes {
+		return false
+	}
+
+	ctx.PushGlobalObject()
+	ctx.pushPackages()
+	deleted := ctx.deleteNewProps(-2, globals.names) &&
+		ctx.deleteNewProps(-1, globals.packages)
+	ctx.Pop2()
+
+	if !deleted {
+		return false
+	}
+
+	changed, lazy := ctx.globalsChanged(globals)
+	if changed {
+		return false
+	}
+
+	if lazy {
+		p.Lock()
+		p.globals[ctx] = ctx.recordGlobals()
+		p.Unlock()
+	}
+
+	return true
+}
+
+// deleteNewProps deletes the own enumerable properties of the object at the
+// given index not found on the given names, returns false if any cannot be
+// deleted.
+func (ctx *Context) deleteNewProps(index int, names map[string]bool) bool {
+	index = ctx.NormalizeIndex(index)
+	for name := range ctx.getOwnNames(index) {
+		if !names[name] && !ctx.DelPropString(index, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Discard destroys the Context releasing its place in the Pool, it should be
+// used instead of Put when the Context is in a unknown state, like after an
+// error or a timeout. As Put, panics with ErrContextNotTaken if the Context
+// was not taken from the Pool, or was already returned.
+func (p *Pool) Discard(ctx *Context) {
+	p.giveBack(ctx)
+	p.destroy(ctx)
+}
+
+func (p *Pool) destroy(ctx *Context) {
+	p.release(ctx)
+	ctx.DestroyHeap()
+}
+
+// release releases the place of the Context in the Pool, without destroy it.
+func (p *Pool) release(ctx *Context) {
+	p.Lock()
+	delete(p.globals, ctx)
+	p.Unlock()
+
+	<-p.live
+}
+
+// Do runs fn with a Context from the Pool. The Context is returned to the Pool
+// when fn finishes, unless fn returns an error or panics, then is discarded.
+//
+// If c is done before fn finishes, c.Err() is returned and the Context is
+// interrupted: its place in the Pool is released at once and every call to a
+// Go function from the running script fails, stopping it, as the running
+// requests of fetch, the Context is destroyed when fn returns. The Duktape
+// builds have no execution timeout, so a script not calling Go, like an
+// endless loop, keeps running on its own goroutine, but without holding a
+// place in the Pool.
+func (p *Pool) Do(c context.Context, fn func(ctx *Context) error) error {
+	ctx, err := p.get(c.Done())
+	if err == context.Canceled {
+		return c.Err()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		result <- fn(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			p.Discard(ctx)
+			return err
+		}
+
+		p.Put(ctx)
+		return nil
+	case <-c.Done():
+		ctx.interrupt()
+		p.giveBack(ctx)
+		p.release(ctx)
+		go func() {
+			<-result
+			ctx.DestroyHeap()
+		}()
+
+		return c.Err()
+	}
+}
+
+// Close destroys all the idle Contexts, the Contexts in use are destroyed when
+// they are returned.
+func (p *Pool) Close() {
+	p.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.closing)
+	}
+
+	p.Unlock()
+
+	for {
+		select {
+		case ctx := <-p.idle:
+			p.destroy(ctx)
+		default:
+			return
+		}
+	}
+}
+
+func (p *Pool) isClosed() bool {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.closed
+}
+
+func (ctx *Context) getGlobalNames() map[string]bool {
+	ctx.PushGlobalObject()
+	defer ctx.Pop()
+
+	return ctx.getOwnNames(-1)
+}
+
+func (ctx *Context) getPackageNames() map[string]bool {
+	ctx.pushPackages()
+	defer ctx.Pop()
+
+	return ctx.getOwnNames(-1)
+}
+
+// getOwnNames returns the names of the own enumerable properties of the
+// object at the given index.
+func (ctx *Context) getOwnNames(index int) map[string]bool {
+	names := make(map[string]bool, 0)
+
+	ctx.Enum(index, duktape.EnumOwnPropertiesOnly)
+	for ctx.Next(-1, false) {
+		names[ctx.SafeToString(-1)] = true
+		ctx.Pop()
+	}
+
+	ctx.Pop()
+	return names
+}
+
+// interrupt makes fail every Go function called from the Context, stopping the
+// running script on its next call to Go, and cancels the running requests of
+// fetch.
+func (ctx *Context) interrupt() {
+	atomic.StoreInt32(&ctx.interrupted, 1)
+	ctx.cancel()
+}
+
+func (ctx *Context) isInterrupted() bool {
+	return atomic.LoadInt32(&ctx.interrupted) == 1
+}
+
+// globalsState is a record of the properties of the objects reachable from the
+// global object and the cached packages after the setup of a Context,
+// including the global names and the names of the packages.
+type globalsState struct {
+	names    map[string]bool
+	packages map[string]bool
+	props    map[stateKey]stateProp
+	// externalBytes is the number of external bytes pushed by the setup
+	externalBytes int
+}
+
+// stateKey identifies a property, proto is true for the prototype.
+type stateKey struct {
+	obj   unsafe.Pointer
+	name  string
+	proto bool
+}
+
+// stateProp is a property as given by Object.getOwnPropertyDescriptor, lazy
+// are the properties defined by PutPropStringLazy not read yet.
+type stateProp struct {
+	value, getter, setter stateValue
+	flags                 int
+	lazy                  bool
+}
+
+// stateValue identifies a value, being the objects identified by its heap
+// pointer.
+type stateValue struct {
+	t      duktape.Type
+	number uint64
+	str    string
+	ptr    unsafe.Pointer
+}
+
+// lazyGetterProp marks the getters of the properties defined by
+// PutPropStringLazy.
+const lazyGetterProp = "\xff" + "lazyGetterProp"
+
+func (ctx *Context) recordGlobals() *globalsState {
+	s := &globalsState{
+		names:         ctx.getGlobalNames(),
+		packages:      ctx.getPackageNames(),
+		props:         make(map[stateKey]stateProp, 0),
+		externalBytes: len(ctx.externalBytes),
+	}
+
+	ctx.walkGlobals(func(k stateKey, p stateProp) bool {
+		s.props[k] = p
+		return true
+	})
+
+	return s
+}
+
+// globalsChanged returns if any property differs from the recorded ones, lazy
+// is true when some lazy property was read since then, so the properties have
+// to be recorded again. The values of the read lazy properties are not walked,
+// and the objects not reachable anymore, like its getters, are not compared.
+func (ctx *Context) globalsChanged(s *globalsState) (changed, lazy bool) {
+	var found int
+	objects := make(map[unsafe.Pointer]bool, 0)
+	ctx.walkGlobals(func(k stateKey, p stateProp) bool {
+		objects[k.obj] = true
+		recorded, ok := s.props[k]
+		switch {
+		case !ok:
+			changed = true
+		case recorded.lazy && !p.lazy:
+			found++
+			lazy = true
+			return false
+		case recorded != p:
+			changed = true
+		default:
+			found++
+		}
+
+		return !changed
+	})
+
+	if changed {
+		return true, lazy
+	}
+
+	for k := range s.props {
+		if objects[k.obj] {
+			found--
+		}
+	}
+
+	return found != 0, lazy
+}
+
+// walkGlobals calls f with every property of the objects reachable from the
+// global object and the cached packages, their prototypes are given as a
+// property with proto set. The values of the properties are walked if f
+// returns true, excluding the proxied Go values. The buffers are walked
+// without its indexes.
+func (ctx *Context) walkGlobals(f func(stateKey, stateProp) bool) {
+	top := ctx.GetTop()
+	defer ctx.SetTop(top)
+
+	ctx.Context.PevalString(describeJS)
+	describe := ctx.NormalizeIndex(-1)
+
+	ctx.PushGlobalObject()
+	ctx.pushPackages()
+	pending := []unsafe.Pointer{ctx.GetHeapptr(-1), ctx.GetHeapptr(-2)}
+	visited := map[unsafe.Pointer]bool{pending[0]: true, pending[1]: true}
+	ctx.Pop2()
+
+	for len(pending) > 0 {
+		obj := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		ctx.Dup(describe)
+		ctx.PushHeapptr(obj)
+		if ctx.Pcall(1) != 0 || !ctx.IsArray(-1) {
+			ctx.Pop()
+			continue
+		}
+
+		props := ctx.NormalizeIndex(-1)
+		length := ctx.GetLength(props)
+		for i := 0; i+4 < length; i += 5 {
+			for j := 0; j < 5; j++ {
+				ctx.GetPropIndex(props, uint(i+j))
+			}
+
+			p := stateProp{
+				value:  ctx.stateValue(-4),
+				getter: ctx.stateValue(-3),
+				setter: ctx.stateValue(-2),
+				flags:  ctx.GetInt(-1),
+			}
+
+			if ctx.IsObject(-3) {
+				p.lazy = ctx.GetPropString(-3, lazyGetterProp)
+				ctx.Pop()
+			}
+
+			key := stateKey{obj: obj, name: ctx.GetString(-5), proto: i == 0}
+			if f(key, p) {
+				for _, index := range []int{-4, -3, -2} {
+					ptr := ctx.GetHeapptr(index)
+					if ctx.IsObject(index) && !visited[ptr] && ctx.getProxy(index) == nil {
+						visited[ptr] = true
+						pending = append(pending, ptr)
+					}
+				}
+			}
+
+			ctx.PopN(5)
+		}
+
+		ctx.Pop()
+	}
+}
+
+func (ctx *Context) stateValue(index int) stateValue {
+	v := stateValue{t: ctx.GetType(index)}
+	switch {
+	case ctx.IsBoolean(index):
+		if ctx.GetBoolean(index) {
+			v.number = 1
+		}
+	case ctx.IsNumber(index):
+		v.number = math.Float64bits(ctx.GetNumber(index))
+	case ctx.IsString(index):
+		v.str = ctx.GetString(index)
+	case ctx.IsPointer(index):
+		v.ptr = ctx.GetPointer(index)
+	default:
+		v.ptr = ctx.GetHeapptr(index)
+	}
+
+	return v
+}
+
+// describeJS returns the prototype and the own properties of an object, as a
+// flat array of name, value, getter, setter and attributes, the first one is
+// the prototype.
+const describeJS = `(function (o) {
+	var props = ['', Object.getPrototypeOf(o), undefined, undefined, 0];
+	if (o instanceof ArrayBuffer || ArrayBuffer.isView(o)) {
+		return props;
+	}
+
+	Object.getOwnPropertyNames(o).forEach(function (name) {
+		var d = Object.getOwnPropertyDescriptor(o, name);
+		props.push(name, d.value, d.get, d.set,
+			(d.writable ? 1 : 0) | (d.enumerable ? 2 : 0) | (d.configurable ? 4 : 0));
+	});
+
+	return props;
+})`
@@ -0,0 +1,445 @@
+package candyjs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestNewPool(c *C) {
+	var calls int
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error {
+			calls++
+			return ctx.PevalString(`foo = 42`)
+		},
+		Warm: 2,
+		Max:  4,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	c.Assert(calls, Equals, 2)
+	c.Assert(p.idle, HasLen, 2)
+	c.Assert(p.live, HasLen, 2)
+}
+
+func (s *CandySuite) TestNewPool_SetupError(c *C) {
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error { return errors.New("foo") },
+		Warm:  1,
+		Max:   1,
+	})
+
+	c.Assert(p, IsNil)
+	c.Assert(err, ErrorMatches, "foo")
+}
+
+func (s *CandySuite) TestNewPool_InvalidMax(c *C) {
+	_, err := NewPool(PoolConfig{})
+	c.Assert(err, NotNil)
+}
+
+func (s *CandySuite) TestPool_GetPut(c *C) {
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error { return ctx.PevalString(`foo = 42`) },
+		Max:   1,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	ctx, err := p.Get()
+	c.Assert(err, IsNil)
+	c.Assert(ctx.PevalString(`qux = foo; var bar = 1`), IsNil)
+	p.Put(ctx)
+
+	other, err := p.Get()
+	c.Assert(err, IsNil)
+	c.Assert(other, Equals, ctx)
+	c.Assert(ctx.GetTop(), Equals, 0)
+
+	c.Assert(ctx.PevalString(`[typeof qux, typeof bar, foo].join()`), IsNil)
+	c.Assert(ctx.GetString(-1), Equals, "undefined,undefined,42")
+	c.Assert(ctx.PevalString(`foo = 21`), IsNil)
+	p.Put(ctx)
+
+	other, err = p.Get()
+	c.Assert(err, IsNil)
+	c.Assert(other, Not(Equals), ctx)
+
+	c.Assert(other.PevalString(`foo`), IsNil)
+	c.Assert(other.GetInt(-1), Equals, 42)
+	p.Put(other)
+}
+
+func (s *CandySuite) TestPool_GetBlocks(c *C) {
+	p, err := NewPool(PoolConfig{Max: 1})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	ctx, err := p.Get()
+	c.Assert(err, IsNil)
+
+	got := make(chan *Context)
+	go func() {
+		other, _ := p.Get()
+		got <- other
+	}()
+
+	select {
+	case <-got:
+		c.Fatal("Get should block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	p.Discard(ctx)
+	other := <-got
+	c.Assert(other, NotNil)
+	c.Assert(other, Not(Equals), ctx)
+	p.Put(other)
+}
+
+func (s *CandySuite) TestPool_Do(c *C) {
+	p, err := NewPool(PoolConfig{Warm: 1, Max: 1})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	var used *Context
+	err = p.Do(context.Background(), func(ctx *Context) error {
+		used = ctx
+		return ctx.PevalString(`1 + 1`)
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(p.idle, HasLen, 1)
+
+	err = p.Do(context.Background(), func(ctx *Context) error {
+		c.Assert(ctx, Equals, used)
+		return ctx.PevalString(`throw new Error("foo")`)
+	})
+
+	c.Assert(err, NotNil)
+	c.Assert(p.idle, HasLen, 0)
+	c.Assert(p.live, HasLen, 0)
+}
+
+func (s *CandySuite) TestPool_DoPanic(c *C) {
+	p, err := NewPool(PoolConfig{Max: 1})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	err = p.Do(context.Background(), func(ctx *Context) error {
+		panic("foo")
+	})
+
+	c.Assert(err, ErrorMatches, "panic: foo")
+	c.Assert(p.live, HasLen, 0)
+}
+
+func (s *CandySuite) TestPool_DoTimeout(c *C) {
+	p, err := NewPool(PoolConfig{Max: 1})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	release := make(chan bool)
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = p.Do(timeout, func(ctx *Context) error {
+		<-release
+		return nil
+	})
+
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	close(release)
+
+	ctx, err := p.Get()
+	c.Assert(err, IsNil)
+	p.Put(ctx)
+}
+
+func (s *CandySuite) TestPool_Close(c *C) {
+	p, err := NewPool(PoolConfig{Warm: 2, Max: 2})
+	c.Assert(err, IsNil)
+
+	p.Close()
+	c.Assert(p.live, HasLen, 0)
+
+	_, err = p.Get()
+	c.Assert(err, Equals, ErrPoolClosed)
+}
+
+func (s *CandySuite) TestPool_PutModified(c *C) {
+	var calls int
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error {
+			calls++
+			return ctx.PevalString(`var config = {debug: false, list: [1]}`)
+		},
+		Max: 1,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	for _, js := range []string{
+		`config.debug = true`,
+		`config.list.push(2)`,
+		`delete config.debug`,
+		`Object.prototype.foo = 42`,
+		`CandyJS.foo = 42`,
+		`Object.defineProperty(config, 'debug', {get: function() { return true; }})`,
+		`Object.freeze(config)`,
+		`Object.setPrototypeOf(config, Array.prototype)`,
+	} {
+		ctx, err := p.Get()
+		c.Assert(err, IsNil)
+		c.Assert(ctx.PevalString(js), IsNil)
+		p.Put(ctx)
+
+		ctx, err = p.Get()
+		c.Assert(err, IsNil)
+		c.Assert(ctx.PevalString(`[config.debug, config.list.length, ({}).foo, CandyJS.foo].join()`), IsNil)
+		c.Assert(ctx.GetString(-1), Equals, "false,1,,", Commentf(js))
+		p.Put(ctx)
+	}
+
+	c.Assert(calls, Equals, 9)
+}
+
+func (s *CandySuite) TestPool_PutUnmodified(c *C) {
+	var calls int
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error {
+			calls++
+			return ctx.PevalString(`var config = {debug: false}`)
+		},
+		Max: 1,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	for _, js := range []string{
+		`var foo = config.debug; bar = {}`,
+		`btoa('foo') + new TextEncoder().encode('foo').length`,
+		`btoa('foo')`,
+		`print.call(null)`,
+	} {
+		ctx, err := p.Get()
+		c.Assert(err, IsNil)
+		c.Assert(ctx.PevalString(js), IsNil)
+		p.Put(ctx)
+	}
+
+	c.Assert(calls, Equals, 1)
+}
+
+func (s *CandySuite) TestPool_PutExternalBytes(c *C) {
+	var calls int
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error {
+			calls++
+			ctx.PushGlobalGoFunction("data", func() ExternalBytes {
+				return ExternalBytes("foo")
+			})
+
+			return ctx.PushGlobalInterface("setup", ExternalBytes("bar"))
+		},
+		Max: 1,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	for _, js := range []string{`setup.length`, `data().length`, `setup.length`} {
+		ctx, err := p.Get()
+		c.Assert(err, IsNil)
+		c.Assert(ctx.PevalString(js), IsNil)
+		c.Assert(ctx.GetInt(-1), Equals, 3)
+		p.Put(ctx)
+	}
+
+	c.Assert(calls, Equals, 2)
+}
+
+func (s *CandySuite) TestPool_PutPackages(c *C) {
+	RegisterPackagePusher("foo", func(ctx *Context) {
+		ctx.PevalString(`({a: 'foo'})`)
+	})
+
+	var calls int
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error {
+			calls++
+			return ctx.PevalString(`CandyJS.require('foo')`)
+		},
+		Max: 1,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	for _, js := range []string{`CandyJS.require('foo').a = 'bar'`, `1`} {
+		ctx, err := p.Get()
+		c.Assert(err, IsNil)
+		c.Assert(ctx.PevalString(`CandyJS.require('foo').a`), IsNil)
+		c.Assert(ctx.GetString(-1), Equals, "foo")
+		c.Assert(ctx.PevalString(js), IsNil)
+		p.Put(ctx)
+	}
+
+	c.Assert(calls, Equals, 2)
+
+	p, err = NewPool(PoolConfig{Max: 1})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	ctx, err := p.Get()
+	c.Assert(err, IsNil)
+	c.Assert(ctx.PevalString(`CandyJS.require('foo').a = 'bar'`), IsNil)
+	p.Put(ctx)
+
+	other, err := p.Get()
+	c.Assert(err, IsNil)
+	c.Assert(other, Equals, ctx)
+	c.Assert(ctx.PevalString(`CandyJS.require('foo').a`), IsNil)
+	c.Assert(ctx.GetString(-1), Equals, "foo")
+	p.Put(ctx)
+}
+
+func (s *CandySuite) TestPool_PutNotTaken(c *C) {
+	p, err := NewPool(PoolConfig{Max: 1})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	ctx := NewContext()
+	defer ctx.DestroyHeap()
+
+	c.Assert(func() { p.Put(ctx) }, PanicMatches, ErrContextNotTaken.Error())
+	c.Assert(func() { p.Discard(ctx) }, PanicMatches, ErrContextNotTaken.Error())
+
+	ctx, err = p.Get()
+	c.Assert(err, IsNil)
+	p.Put(ctx)
+	c.Assert(func() { p.Put(ctx) }, PanicMatches, ErrContextNotTaken.Error())
+	c.Assert(func() { p.Discard(ctx) }, PanicMatches, ErrContextNotTaken.Error())
+
+	ctx, err = p.Get()
+	c.Assert(err, IsNil)
+	p.Discard(ctx)
+	c.Assert(func() { p.Discard(ctx) }, PanicMatches, ErrContextNotTaken.Error())
+
+	ctx, err = p.Get()
+	c.Assert(err, IsNil)
+	p.Put(ctx)
+}
+
+func (s *CandySuite) TestPool_CloseBlockedGet(c *C) {
+	p, err := NewPool(PoolConfig{Max: 1})
+	c.Assert(err, IsNil)
+
+	ctx, err := p.Get()
+	c.Assert(err, IsNil)
+
+	got := make(chan error)
+	go func() {
+		_, err := p.Get()
+		got <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+	c.Assert(<-got, Equals, ErrPoolClosed)
+
+	p.Put(ctx)
+	c.Assert(p.live, HasLen, 0)
+}
+
+func (s *CandySuite) TestPool_DoInterrupt(c *C) {
+	p, err := NewPool(PoolConfig{
+		Setup: func(ctx *Context) error {
+			_, err := ctx.PushGlobalGoFunction("tick", func() {})
+			return err
+		},
+		Max: 1,
+	})
+
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	timeout, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	err = p.Do(timeout, func(ctx *Context) error {
+		err := ctx.PevalString(`while (true) { tick(); }`)
+		stopped <- err
+		return err
+	})
+
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	c.Assert(p.live, HasLen, 0)
+
+	err = p.Do(context.Background(), func(ctx *Context) error {
+		return ctx.PevalString(`tick()`)
+	})
+
+	c.Assert(err, IsNil)
+
+	select {
+	case err := <-stopped:
+		c.Assert(err, NotNil)
+	case <-time.After(time.Second):
+		c.Fatal("the script was not interrupted")
+	}
+}
+
+func (s *CandySuite) TestPool_DoCancelsFetch(c *C) {
+	cancelled := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			cancelled <- true
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewPool(PoolConfig{Max: 1, Options: Options{Fetch: http.DefaultTransport}})
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	timeout, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	err = p.Do(timeout, func(ctx *Context) error {
+		ctx.PushGlobalInterface("url", server.URL)
+		err := ctx.PevalString(`
+			var error;
+			fetch(url).catch(function (err) { error = err; });
+			if (error) { throw error; }
+		`)
+
+		stopped <- err
+		return err
+	})
+
+	c.Assert(err, Equals, context.DeadlineExceeded)
+
+	select {
+	case err := <-stopped:
+		c.Assert(err, ErrorMatches, ".*context canceled.*")
+	case <-time.After(time.Second):
+		c.Fatal("the fetch was not cancelled")
+	}
+
+	c.Assert(<-cancelled, Equals, true)
+}
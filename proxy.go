@@ -2,6 +2,7 @@ package candyjs
 
 import "C"
 import (
+	"encoding/json"
 	"errors"
 	"reflect"
 )
@@ -10,6 +11,10 @@ import (
 // javascript cannot be found, basically a valid method or field cannot found.
 var ErrUndefinedProperty = errors.New("undefined property")
 
+// ErrCyclicValue is returned serializing to JSON a proxied value containing
+// itself.
+var ErrCyclicValue = errors.New("cyclic value")
+
 var (
 	p = &proxy{}
 
@@ -17,7 +22,7 @@ var (
 	//throw an error, the value of the map is the value returned when this keys
 	//are requested.
 	internalKeys = map[string]interface{}{
-		"toJSON": nil, "valueOf": nil,
+		"valueOf":  nil,
 		"toString": func() string { return "[candyjs Proxy]" },
 	}
 )
@@ -32,7 +37,11 @@ func (p *proxy) has(t interface{}, k string) bool {
 func (p *proxy) get(t interface{}, k string, recv interface{}) (interface{}, error) {
 	f, err := p.getProperty(t, k)
 	if err != nil {
-		if v, isInternal := internalKeys[k]; isInternal {
+		if k == "toJSON" {
+			return p.toJSON(t), nil
+		}
+
+		if v, isInternal := internalKeys[k]; isInternal || isSymbol(k) {
 			return v, nil
 		}
 
@@ -55,6 +64,9 @@ func (p *proxy) set(t interface{}, k string, v, recv interface{}) (bool, error)
 	value := reflect.Zero(f.Type())
 	if v != nil {
 		value = reflect.ValueOf(castNumberToGoType(f.Kind(), v))
+		if isBytes(f.Type()) && value.Type().ConvertibleTo(f.Type()) {
+			value = value.Convert(f.Type())
+		}
 	}
 
 	f.Set(value)
@@ -62,6 +74,10 @@ func (p *proxy) set(t interface{}, k string, v, recv interface{}) (bool, error)
 }
 
 func (p *proxy) getProperty(t interface{}, key string) (reflect.Value, error) {
+	if isSymbol(key) {
+		return reflect.Value{}, ErrUndefinedProperty
+	}
+
 	v := reflect.ValueOf(t)
 	r, found := p.getValueFromKind(key, v)
 	if !found {
@@ -100,15 +116,12 @@ func (p *proxy) getValueFromKindPtr(key string, v reflect.Value) (reflect.Value,
 }
 
 func (p *proxy) getValueFromKindStruct(key string, v reflect.Value) (reflect.Value, bool) {
-	var r reflect.Value
-	for _, name := range nameToGo(key) {
-		r = v.FieldByName(name)
-		if r.IsValid() {
-			break
-		}
+	index, found := types.get(v.Type()).field(key)
+	if !found {
+		return reflect.Value{}, false
 	}
 
-	return r, r.IsValid()
+	return v.FieldByIndex(index), true
 }
 
 func (p *proxy) getValueFromKindMap(key string, v reflect.Value) (reflect.Value, bool) {
@@ -119,55 +132,102 @@ func (p *proxy) getValueFromKindMap(key string, v reflect.Value) (reflect.Value,
 }
 
 func (p *proxy) getMethod(key string, v reflect.Value) (reflect.Value, bool) {
-	var r reflect.Value
-	for _, name := range nameToGo(key) {
-		r = v.MethodByName(name)
-		if r.IsValid() {
-			break
-		}
+	index, found := types.get(v.Type()).method(key)
+	if !found {
+		return reflect.Value{}, false
 	}
 
-	return r, r.IsValid()
+	return v.Method(index), true
 }
 
 func (p *proxy) enumerate(t interface{}) (interface{}, error) {
 	return p.getPropertyNames(t)
 }
 
-func (p *proxy) getPropertyNames(t interface{}) ([]string, error) {
-	v := reflect.ValueOf(t)
+// isSymbol returns if the key is a Symbol, like the Symbol.toPrimitive read by
+// Duktape when a proxy is coerced to a primitive value. The symbols are
+// strings starting with a byte not valid on UTF-8, being read as an empty
+// string when are decoded from JSON, a name never found on Go.
+func isSymbol(k string) bool {
+	return k == "" || k[0] >= 0x80 && k[0] <= 0x82 || k[0] == 0xff
+}
 
-	var names []string
-	var err error
-	switch v.Kind() {
-	case reflect.Ptr:
-		names, err = p.getPropertyNames(v.Elem().Interface())
-		if err != nil {
-			return nil, err
+// toJSON returns the toJSON method of the proxied value, called with the key
+// by JSON.stringify. Duktape only serializes the properties of the target of
+// a proxy, so the fields are returned by their JavaScript names, as the ones
+// of the nested structs.
+func (p *proxy) toJSON(t interface{}) func(string) (interface{}, error) {
+	return func(string) (interface{}, error) {
+		return p.jsonValue(reflect.ValueOf(t), make(map[uintptr]bool, 0))
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func (p *proxy) jsonValue(v reflect.Value, seen map[uintptr]bool) (interface{}, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
 		}
+
+		if v.Kind() == reflect.Ptr {
+			if seen[v.Pointer()] {
+				return nil, ErrCyclicValue
+			}
+
+			seen[v.Pointer()] = true
+			defer delete(seen, v.Pointer())
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Type().Implements(jsonMarshalerType) {
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
 	case reflect.Struct:
-		cFields := v.NumField()
-		for i := 0; i < cFields; i++ {
-			fieldName := v.Type().Field(i).Name
-			if !isExported(fieldName) {
+		m := make(map[string]interface{}, 0)
+		for key, index := range types.get(v.Type()).fieldsByKey {
+			f, err := v.FieldByIndexErr(index)
+			if err != nil || !isJSONKind(f.Kind()) {
 				continue
 			}
 
-			names = append(names, nameToJavaScript(fieldName))
+			if m[key], err = p.jsonValue(f, seen); err != nil {
+				return nil, err
+			}
 		}
-	}
 
-	mCount := v.NumMethod()
-	for i := 0; i < mCount; i++ {
-		methodName := v.Type().Method(i).Name
-		if !isExported(methodName) {
-			continue
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+
+		l := make([]interface{}, v.Len())
+		for i := range l {
+			var err error
+			if l[i], err = p.jsonValue(v.Index(i), seen); err != nil {
+				return nil, err
+			}
 		}
 
-		names = append(names, nameToJavaScript(methodName))
+		return l, nil
 	}
 
-	return names, nil
+	return v.Interface(), nil
+}
+
+// isJSONKind returns if the values of the kind are serialized to JSON, as
+// JSON.stringify skips the functions.
+func isJSONKind(k reflect.Kind) bool {
+	return k != reflect.Func && k != reflect.Chan && k != reflect.UnsafePointer
+}
+
+func (p *proxy) getPropertyNames(t interface{}) ([]string, error) {
+	return types.get(reflect.TypeOf(t)).names, nil
 }
 
 func castNumberToGoType(k reflect.Kind, v interface{}) interface{} {
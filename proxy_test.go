@@ -24,9 +24,13 @@ func (s *CandySuite) TestProxy_GetUndefinedProperty(c *C) {
 }
 
 func (s *CandySuite) TestProxy_GetInternal(c *C) {
-	v, err := p.get(&MyStruct{Int: 42}, "toJSON", nil)
+	v, err := p.get(&MyStruct{Int: 42}, "valueOf", nil)
 	c.Assert(err, IsNil)
 	c.Assert(v, Equals, nil)
+
+	v, err = p.get(&MyStruct{Int: 42}, "toJSON", nil)
+	c.Assert(err, IsNil)
+	c.Assert(v, FitsTypeOf, func(string) (interface{}, error) { return nil, nil })
 }
 
 func (s *CandySuite) TestProxy_Set(c *C) {
@@ -144,6 +148,20 @@ func (s *CandySuite) TestProxyInternalKeys(c *C) {
 	c.Assert(r["int"], Equals, 142.0)
 }
 
+func (s *CandySuite) TestProxy_ToJSON(c *C) {
+	v := &MyStruct{Int: 42, Nested: &MyStruct{String: "foo"}}
+	s.ctx.PushGlobalProxy("foo", v)
+
+	c.Assert(s.ctx.PevalString(`
+		var json = JSON.parse(JSON.stringify(foo));
+		[json.int, json.nested.string, json.empty, json.multiply].join()
+	`), IsNil)
+	c.Assert(s.ctx.GetString(-1), Equals, "42,foo,,")
+
+	v.Nested.Nested = v
+	c.Assert(s.ctx.PevalString(`JSON.stringify(foo)`), NotNil)
+}
+
 type customInt int
 
 func (c customInt) FunctionWithoutPtr() {}
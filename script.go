@@ -0,0 +1,207 @@
+package candyjs
+
+import "C"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// Script is a JavaScript program compiled to Duktape bytecode, it can be
+// executed on any Context without parsing it again.
+// http://duktape.org/guide.html#bytecodedumpload
+type Script struct {
+	Filename string
+	Bytecode []byte
+}
+
+// Compile compiles the given source to bytecode, the filename is used on the
+// error messages and stack traces. The source is evaluated as a program, in
+// the same way as PevalString does.
+func (ctx *Context) Compile(filename, src string) (*Script, error) {
+	src, err := ctx.transform(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.compile(filename, src)
+}
+
+// compile compiles the given source, already transformed, to bytecode.
+func (ctx *Context) compile(filename, src string) (*Script, error) {
+	ctx.PushString(filename)
+	if err := ctx.PcompileStringFilename(0, src); err != nil {
+		defer ctx.Pop()
+		return nil, ctx.getError(-1)
+	}
+
+	ctx.DumpFunction()
+	defer ctx.Pop()
+
+	ptr, size := ctx.GetBuffer(-1)
+
+	return &Script{
+		Filename: filename,
+		Bytecode: C.GoBytes(ptr, C.int(size)),
+	}, nil
+}
+
+// Run loads the bytecode into the given Context and executes it, the result is
+// left on the stack as the Peval functions does. The Script can be run on many
+// Contexts.
+func (s *Script) Run(ctx *Context) error {
+	ptr := ctx.PushFixedBuffer(len(s.Bytecode))
+	copy(unsafe.Slice((*byte)(ptr), len(s.Bytecode)), s.Bytecode)
+
+	ctx.LoadFunction()
+	if ctx.Pcall(0) != 0 {
+		return ctx.getError(-1)
+	}
+
+	return nil
+}
+
+// ScriptCache compiles Scripts once per version of its source, the Scripts are
+// keyed by the hash of the filename, the source, as transformed by the
+// Transformer and ESModules Options of the Context, and the version and build
+// configuration of Duktape, and stored in memory and, when a directory is
+// given, on disk, so they survive between executions. The bytecode of other
+// Duktape builds is never loaded, since it is not validated.
+type ScriptCache struct {
+	dir     string
+	scripts map[string]*Script
+	sync.Mutex
+}
+
+// NewScriptCache returns a new ScriptCache storing the bytecode at the given
+// directory, with an empty dir the Scripts are only cached in memory.
+func NewScriptCache(dir string) *ScriptCache {
+	return &ScriptCache{
+		dir:     dir,
+		scripts: make(map[string]*Script, 0),
+	}
+}
+
+// Compile returns the Script for the given source, transformed following the
+// Options of the given Context, if is not cached yet is compiled using it.
+func (c *ScriptCache) Compile(ctx *Context, filename, src string) (*Script, error) {
+	src, err := ctx.transform(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	key := scriptKey(engineID(), filename, src)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if s, ok := c.scripts[key]; ok {
+		return s, nil
+	}
+
+	s, err := c.load(key, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		s, err = ctx.compile(filename, src)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.save(key, s); err != nil {
+			return nil, err
+		}
+	}
+
+	c.scripts[key] = s
+	return s, nil
+}
+
+func (c *ScriptCache) load(key, filename string) (*Script, error) {
+	if c.dir == "" {
+		return nil, nil
+	}
+
+	bytecode, err := ioutil.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Script{Filename: filename, Bytecode: bytecode}, nil
+}
+
+func (c *ScriptCache) save(key string, s *Script) error {
+	if c.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	// a partial written file is never read, Duktape does not validate bytecode
+	tmp, err := ioutil.TempFile(c.dir, key)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(s.Bytecode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *ScriptCache) path(key string) string {
+	return filepath.Join(c.dir, key+".jsc")
+}
+
+func scriptKey(engine, filename, src string) string {
+	h := sha256.New()
+	h.Write([]byte(engine))
+	h.Write([]byte{0})
+	h.Write([]byte(filename))
+	h.Write([]byte{0})
+	h.Write([]byte(src))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var (
+	engineIDOnce sync.Once
+	engineIDStr  string
+)
+
+// engineID returns the version and the build configuration of Duktape, as
+// given by Duktape.version and Duktape.env, the bytecode is only valid for the
+// same ones.
+func engineID() string {
+	engineIDOnce.Do(func() {
+		ctx := duktape.New()
+		defer ctx.DestroyHeap()
+
+		ctx.PevalString(`Duktape.version + ' ' + Duktape.env`)
+		engineIDStr = ctx.SafeToString(-1)
+	})
+
+	return engineIDStr
+}
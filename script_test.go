@@ -0,0 +1,113 @@
+package candyjs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestCompile(c *C) {
+	script, err := s.ctx.Compile("foo.js", `store(21 * 2)`)
+	c.Assert(err, IsNil)
+	c.Assert(script.Filename, Equals, "foo.js")
+	c.Assert(script.Bytecode, Not(HasLen), 0)
+
+	c.Assert(script.Run(s.ctx), IsNil)
+	c.Assert(s.stored, Equals, 42.0)
+}
+
+func (s *CandySuite) TestCompile_SyntaxError(c *C) {
+	script, err := s.ctx.Compile("foo.js", `store(`)
+	c.Assert(script, IsNil)
+	c.Assert(err.(*Error).Type, Equals, "SyntaxError")
+}
+
+func (s *CandySuite) TestScriptRun_OtherContext(c *C) {
+	ctx := NewContext()
+	defer ctx.DestroyHeap()
+
+	script, err := ctx.Compile("foo.js", `store(typeof foo)`)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.ctx.PevalString(`foo = 42`), IsNil)
+	c.Assert(script.Run(s.ctx), IsNil)
+	c.Assert(s.stored, Equals, "number")
+}
+
+func (s *CandySuite) TestScriptRun_Error(c *C) {
+	script, err := s.ctx.Compile("foo.js", "\nthrow new TypeError('qux')")
+	c.Assert(err, IsNil)
+
+	err = script.Run(s.ctx)
+	c.Assert(err.(*Error).Type, Equals, "TypeError")
+	c.Assert(err.(*Error).Message, Equals, "qux")
+	c.Assert(err.(*Error).LineNumber, Equals, 2)
+}
+
+func (s *CandySuite) TestScriptCache_Compile(c *C) {
+	dir, err := ioutil.TempDir("", "candyjs")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	cache := NewScriptCache(dir)
+	a, err := cache.Compile(s.ctx, "foo.js", `store(42)`)
+	c.Assert(err, IsNil)
+
+	b, err := cache.Compile(s.ctx, "foo.js", `store(42)`)
+	c.Assert(err, IsNil)
+	c.Assert(a, Equals, b)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsc"))
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 1)
+
+	fromDisk, err := NewScriptCache(dir).Compile(s.ctx, "foo.js", `store(42)`)
+	c.Assert(err, IsNil)
+	c.Assert(fromDisk.Bytecode, DeepEquals, a.Bytecode)
+	c.Assert(fromDisk.Run(s.ctx), IsNil)
+	c.Assert(s.stored, Equals, 42.0)
+
+	other, err := cache.Compile(s.ctx, "foo.js", `store(21)`)
+	c.Assert(err, IsNil)
+	c.Assert(other, Not(Equals), a)
+}
+
+func (s *CandySuite) TestScriptCache_Options(c *C) {
+	cache := NewScriptCache("")
+
+	ctx := NewContextWithOptions(Options{Transformer: &headerTransformer{}})
+	defer ctx.DestroyHeap()
+
+	_, err := cache.Compile(ctx, "foo.js", `let foo = 42`)
+	c.Assert(err, IsNil)
+
+	_, err = cache.Compile(s.ctx, "foo.js", `let foo = 42`)
+	c.Assert(err, NotNil)
+
+	esm := NewContextWithOptions(Options{ESModules: true})
+	defer esm.DestroyHeap()
+
+	_, err = cache.Compile(esm, "foo.js", `import foo from './foo'`)
+	c.Assert(err, IsNil)
+
+	_, err = cache.Compile(s.ctx, "foo.js", `import foo from './foo'`)
+	c.Assert(err, NotNil)
+}
+
+func (s *CandySuite) TestScriptKey(c *C) {
+	c.Assert(engineID(), Matches, `[0-9]+ .+`)
+	c.Assert(scriptKey("a", "foo.js", "42"), Equals, scriptKey("a", "foo.js", "42"))
+	c.Assert(scriptKey("a", "foo.js", "42"), Not(Equals), scriptKey("b", "foo.js", "42"))
+}
+
+func (s *CandySuite) TestScriptCache_Memory(c *C) {
+	cache := NewScriptCache("")
+	a, err := cache.Compile(s.ctx, "foo.js", `store(42)`)
+	c.Assert(err, IsNil)
+
+	b, err := cache.Compile(s.ctx, "foo.js", `store(42)`)
+	c.Assert(err, IsNil)
+	c.Assert(a, Equals, b)
+}
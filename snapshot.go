@@ -0,0 +1,428 @@
+package candyjs
+
+import "C"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// ErrUnsupportedSnapshotValue is returned when a value reachable from the
+// globals cannot be captured by a Snapshot, like native functions not pushed
+// by candyjs, buffers or instances of built-in objects like Date or Error.
+var ErrUnsupportedSnapshotValue = errors.New("unsupported snapshot value")
+
+// builtinConstructors are the constructors whose instances can't be captured,
+// since its internal state is not reachable from the properties.
+var builtinConstructors = []string{
+	"Boolean", "Number", "String", "Symbol", "Date", "RegExp", "Promise",
+	"Error", "EvalError", "RangeError", "ReferenceError", "SyntaxError",
+	"TypeError", "URIError", "ArrayBuffer", "DataView", "Int8Array",
+	"Uint8Array", "Uint8ClampedArray", "Int16Array", "Uint16Array",
+	"Int32Array", "Uint32Array", "Float32Array", "Float64Array",
+	"Map", "Set", "WeakMap", "WeakSet",
+}
+
+// Snapshot is a copy of the globals defined on a Context, used to create new
+// Contexts with the same state without repeat the setup. The following values
+// are captured:
+//  - Undefined, null, booleans, numbers and strings
+//  - Arrays and objects, including its prototype and cyclic references. The
+//    instances of built-in objects other than Object and Array, like Date,
+//    Error or RegExp, are not supported
+//  - ECMAScript functions as bytecode, with its enumerable properties and
+//    prototype. The closures lose the variables of its scopes
+//  - Go functions and proxified Go values, by reference, so the Contexts
+//    created from the Snapshot share the same Go values
+//
+// Only the enumerable properties are captured and the changes made to the
+// built-in objects are not. The Options of the Context are kept, being used to
+// create the new Contexts.
+type Snapshot struct {
+	globals  []snapshotProperty
+	registry []interface{}
+	options  Options
+}
+
+type snapshotKind int
+
+const (
+	snapshotUndefined snapshotKind = iota
+	snapshotPrimitive
+	snapshotObject
+	snapshotArray
+	snapshotFunction
+	snapshotGoFunction
+	snapshotGoProxy
+	snapshotRef
+)
+
+type snapshotValue struct {
+	kind snapshotKind
+	// value is the Go value of the primitives
+	value interface{}
+	// id identifies the objects, the refs contain the id of the referenced one
+	id int
+	// ref is the index at the registry of the Go values
+	ref       int
+	bytecode  []byte
+	props     []snapshotProperty
+	proto     *snapshotValue
+	prototype *snapshotValue
+}
+
+type snapshotProperty struct {
+	name  string
+	value *snapshotValue
+}
+
+// Snapshot captures the globals defined on the Context, since it was created.
+func (ctx *Context) Snapshot() (*Snapshot, error) {
+	s := &snapshotter{
+		ctx:      ctx,
+		snapshot: &Snapshot{options: ctx.options},
+		objects:  make(map[unsafe.Pointer]int, 0),
+		defaults: make(map[unsafe.Pointer]bool, 0),
+		builtins: make(map[unsafe.Pointer]bool, 0),
+	}
+
+	top := ctx.GetTop()
+	defer ctx.SetTop(top)
+
+	for _, name := range []string{"Object", "Array", "Function"} {
+		ctx.PushGlobalObject()
+		ctx.GetPropString(-1, name)
+		ctx.GetPropString(-1, "prototype")
+		s.defaults[ctx.GetHeapptr(-1)] = true
+		ctx.Pop3()
+	}
+
+	for _, name := range builtinConstructors {
+		ctx.PushGlobalObject()
+		ctx.GetPropString(-1, name)
+		if ctx.IsFunction(-1) {
+			ctx.GetPropString(-1, "prototype")
+			if ctx.IsObject(-1) {
+				s.builtins[ctx.GetHeapptr(-1)] = true
+			}
+
+			ctx.Pop()
+		}
+
+		ctx.Pop2()
+	}
+
+	globals := getDefaultGlobals(ctx.options)
+	ctx.PushGlobalObject()
+	props, err := s.props(-1, "", func(name string) bool {
+		return !globals[name]
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.snapshot.globals = props
+	return s.snapshot, nil
+}
+
+// getDefaultGlobals returns the globals of a new Context created with the
+// given Options.
+func getDefaultGlobals(opts Options) map[string]bool {
+	ctx := NewContextWithOptions(opts)
+	defer ctx.DestroyHeap()
+
+	return ctx.getGlobalNames()
+}
+
+type snapshotter struct {
+	ctx      *Context
+	snapshot *Snapshot
+	objects  map[unsafe.Pointer]int
+	defaults map[unsafe.Pointer]bool
+	// builtins are the prototypes of the builtinConstructors
+	builtins map[unsafe.Pointer]bool
+}
+
+func (s *snapshotter) props(
+	index int, path string, filter func(name string) bool,
+) ([]snapshotProperty, error) {
+	ctx := s.ctx
+	index = ctx.NormalizeIndex(index)
+
+	var props []snapshotProperty
+	ctx.Enum(index, duktape.EnumOwnPropertiesOnly)
+	defer ctx.Pop()
+
+	for ctx.Next(-1, true) {
+		name := ctx.SafeToString(-2)
+		if filter != nil && !filter(name) {
+			ctx.Pop2()
+			continue
+		}
+
+		v, err := s.value(-1, joinPath(path, name))
+		ctx.Pop2()
+		if err != nil {
+			return nil, err
+		}
+
+		props = append(props, snapshotProperty{name: name, value: v})
+	}
+
+	return props, nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func (s *snapshotter) value(index int, path string) (*snapshotValue, error) {
+	ctx := s.ctx
+	index = ctx.NormalizeIndex(index)
+
+	switch {
+	case ctx.IsUndefined(index):
+		return &snapshotValue{kind: snapshotUndefined}, nil
+	case ctx.IsNull(index):
+		return &snapshotValue{kind: snapshotPrimitive}, nil
+	case ctx.IsBoolean(index):
+		return &snapshotValue{kind: snapshotPrimitive, value: ctx.GetBoolean(index)}, nil
+	case ctx.IsNumber(index):
+		return &snapshotValue{kind: snapshotPrimitive, value: ctx.GetNumber(index)}, nil
+	case ctx.IsString(index):
+		return &snapshotValue{kind: snapshotPrimitive, value: ctx.GetString(index)}, nil
+	case !ctx.IsObject(index):
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSnapshotValue, path)
+	}
+
+	ptr := ctx.GetHeapptr(index)
+	if id, ok := s.objects[ptr]; ok {
+		return &snapshotValue{kind: snapshotRef, id: id}, nil
+	}
+
+	v := &snapshotValue{id: len(s.objects)}
+	s.objects[ptr] = v.id
+
+	if proxy := ctx.getProxy(index); proxy != nil {
+		v.kind = snapshotGoProxy
+		v.ref = s.register(proxy)
+		return v, nil
+	}
+
+	var err error
+	switch {
+	case ctx.IsFunction(index):
+		err = s.function(v, index, path)
+	case ctx.IsArray(index):
+		v.kind = snapshotArray
+		v.props, err = s.props(index, path, nil)
+	default:
+		v.kind = snapshotObject
+		err = s.object(v, index, path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (s *snapshotter) function(v *snapshotValue, index int, path string) error {
+	ctx := s.ctx
+	if f := ctx.getGoFunctionRef(index); f != nil {
+		v.kind = snapshotGoFunction
+		v.ref = s.register(f)
+		return nil
+	}
+
+	if !ctx.IsEcmascriptFunction(index) || ctx.IsBoundFunction(index) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedSnapshotValue, path)
+	}
+
+	v.kind = snapshotFunction
+
+	ctx.Dup(index)
+	ctx.DumpFunction()
+	ptr, size := ctx.GetBuffer(-1)
+	v.bytecode = C.GoBytes(ptr, C.int(size))
+	ctx.Pop()
+
+	var err error
+	v.props, err = s.props(index, path, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx.GetPropString(index, "prototype")
+	defer ctx.Pop()
+	if !ctx.IsObject(-1) {
+		return nil
+	}
+
+	v.prototype, err = s.value(-1, joinPath(path, "prototype"))
+	return err
+}
+
+func (s *snapshotter) object(v *snapshotValue, index int, path string) error {
+	ctx := s.ctx
+
+	ctx.GetPrototype(index)
+	if ctx.IsObject(-1) && s.builtins[ctx.GetHeapptr(-1)] {
+		ctx.Pop()
+		return fmt.Errorf("%w: %s", ErrUnsupportedSnapshotValue, path)
+	}
+
+	if ctx.IsObject(-1) && !s.defaults[ctx.GetHeapptr(-1)] {
+		proto, err := s.value(-1, joinPath(path, "__proto__"))
+		if err != nil {
+			ctx.Pop()
+			return err
+		}
+
+		v.proto = proto
+	}
+
+	ctx.Pop()
+
+	var err error
+	v.props, err = s.props(index, path, nil)
+	return err
+}
+
+func (s *snapshotter) register(v interface{}) int {
+	s.snapshot.registry = append(s.snapshot.registry, v)
+	return len(s.snapshot.registry) - 1
+}
+
+// NewContextFromSnapshot returns a new Context with the globals captured on
+// the given Snapshot, created with the Options of the captured Context.
+func NewContextFromSnapshot(s *Snapshot) *Context {
+	ctx := NewContextWithOptions(s.options)
+	r := &restorer{ctx: ctx, snapshot: s}
+
+	r.stash = ctx.PushArray()
+	ctx.PushGlobalObject()
+	for _, p := range s.globals {
+		r.push(p.value)
+		ctx.PutPropString(-2, p.name)
+	}
+
+	ctx.Pop2()
+	return ctx
+}
+
+type restorer struct {
+	ctx      *Context
+	snapshot *Snapshot
+	// stash is the index of the array containing the restored objects
+	stash int
+}
+
+func (r *restorer) push(v *snapshotValue) {
+	ctx := r.ctx
+
+	switch v.kind {
+	case snapshotUndefined:
+		ctx.PushUndefined()
+	case snapshotPrimitive:
+		r.pushPrimitive(v.value)
+	case snapshotRef:
+		ctx.GetPropIndex(r.stash, uint(v.id))
+	case snapshotGoProxy:
+		ctx.PushProxy(r.snapshot.registry[v.ref])
+		r.register(v)
+	case snapshotGoFunction:
+		switch f := r.snapshot.registry[v.ref].(type) {
+		case typeConstructor:
+			ctx.PushType(reflect.Zero(f.t).Interface())
+		case genericFunction:
+			ctx.PushGenericFunction(f...)
+		default:
+			ctx.PushGoFunction(f)
+		}
+
+		r.register(v)
+	case snapshotArray:
+		ctx.PushArray()
+		r.register(v)
+		r.putProps(v.props)
+	case snapshotObject:
+		ctx.PushObject()
+		r.fillObject(v)
+	case snapshotFunction:
+		r.pushFunction(v)
+	}
+}
+
+func (r *restorer) pushPrimitive(v interface{}) {
+	switch v := v.(type) {
+	case bool:
+		r.ctx.PushBoolean(v)
+	case float64:
+		r.ctx.PushNumber(v)
+	case string:
+		r.ctx.PushString(v)
+	default:
+		r.ctx.PushNull()
+	}
+}
+
+func (r *restorer) pushFunction(v *snapshotValue) {
+	ctx := r.ctx
+
+	ptr := ctx.PushFixedBuffer(len(v.bytecode))
+	copy(unsafe.Slice((*byte)(ptr), len(v.bytecode)), v.bytecode)
+	ctx.LoadFunction()
+
+	r.register(v)
+	r.putProps(v.props)
+
+	if v.prototype == nil {
+		return
+	}
+
+	// the prototype created by LoadFunction is filled keeping its constructor,
+	// unless the prototype was already restored as other value
+	if v.prototype.kind != snapshotObject {
+		r.push(v.prototype)
+		ctx.PutPropString(-2, "prototype")
+		return
+	}
+
+	ctx.GetPropString(-1, "prototype")
+	r.fillObject(v.prototype)
+	ctx.Pop()
+}
+
+func (r *restorer) fillObject(v *snapshotValue) {
+	ctx := r.ctx
+	r.register(v)
+
+	if v.proto != nil {
+		r.push(v.proto)
+		ctx.SetPrototype(-2)
+	}
+
+	r.putProps(v.props)
+}
+
+func (r *restorer) putProps(props []snapshotProperty) {
+	for _, p := range props {
+		r.push(p.value)
+		r.ctx.PutPropString(-2, p.name)
+	}
+}
+
+func (r *restorer) register(v *snapshotValue) {
+	r.ctx.Dup(-1)
+	r.ctx.PutPropIndex(r.stash, uint(v.id))
+}
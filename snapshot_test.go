@@ -0,0 +1,127 @@
+package candyjs
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestSnapshot_Values(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		str = "foo";
+		num = 42;
+		bool = true;
+		nil = null;
+		undef = undefined;
+		arr = [1, "2", {three: 3}];
+		obj = {foo: {bar: "qux"}};
+	`), IsNil)
+
+	ctx := s.fork(c)
+	c.Assert(ctx.PevalString(`store([
+		str, num, bool, nil, typeof undef, arr[2].three, obj.foo.bar
+	])`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{
+		"foo", 42.0, true, nil, "undefined", 3.0, "qux",
+	})
+}
+
+func (s *CandySuite) TestSnapshot_Functions(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		function Foo(n) { this.n = n; }
+		Foo.prototype.double = function() { return this.n * 2; };
+		Foo.create = function(n) { return new Foo(n); };
+		foo = new Foo(21);
+	`), IsNil)
+
+	ctx := s.fork(c)
+	c.Assert(ctx.PevalString(`store([
+		foo.double(),
+		Foo.create(2).double(),
+		foo instanceof Foo,
+		foo.constructor === Foo
+	])`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{42.0, 4.0, true, true})
+}
+
+func (s *CandySuite) TestSnapshot_References(c *C) {
+	c.Assert(s.ctx.PevalString(`
+		a = {name: "a"};
+		b = {name: "b", a: a};
+		a.b = b;
+		list = [a, b];
+	`), IsNil)
+
+	ctx := s.fork(c)
+	c.Assert(ctx.PevalString(`store([
+		a.b === b, b.a === a, list[0] === a, a.b.a.name
+	])`), IsNil)
+
+	c.Assert(s.stored, DeepEquals, []interface{}{true, true, true, "a"})
+}
+
+func (s *CandySuite) TestSnapshot_Go(c *C) {
+	value := &MyStruct{Int: 42}
+	s.ctx.PushGlobalProxy("proxy", value)
+	s.ctx.PushGlobalGoFunction("multiply", func(a, b int) int {
+		return a * b
+	})
+
+	ctx := s.fork(c)
+	c.Assert(ctx.PevalString(`proxy.int = multiply(proxy.int, 2)`), IsNil)
+	c.Assert(value.Int, Equals, 84)
+}
+
+func (s *CandySuite) TestSnapshot_Unsupported(c *C) {
+	c.Assert(s.ctx.PevalString(`foo = {bar: Math.max}`), IsNil)
+
+	_, err := s.ctx.Snapshot()
+	c.Assert(err, ErrorMatches, "unsupported snapshot value: foo.bar")
+}
+
+func (s *CandySuite) TestSnapshot_UnsupportedBuiltins(c *C) {
+	for _, js := range []string{
+		`new Date()`, `new Error("foo")`, `/foo/`, `new Uint8Array(2)`,
+		`new Boolean(true)`, `Object.create(new TypeError())`,
+	} {
+		ctx := NewContext()
+		c.Assert(ctx.PevalString(`foo = {bar: `+js+`}`), IsNil)
+
+		_, err := ctx.Snapshot()
+		c.Assert(errors.Is(err, ErrUnsupportedSnapshotValue), Equals, true, Commentf(js))
+		ctx.DestroyHeap()
+	}
+}
+
+func (s *CandySuite) TestSnapshot_Options(c *C) {
+	stdout := bytes.NewBuffer(nil)
+	ctx := NewContextWithOptions(Options{Stdout: stdout, Fetch: http.DefaultTransport})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`foo = 42`), IsNil)
+
+	snapshot, err := ctx.Snapshot()
+	c.Assert(err, IsNil)
+
+	other := NewContextFromSnapshot(snapshot)
+	defer other.DestroyHeap()
+
+	c.Assert(other.PevalString(`print(typeof fetch, foo)`), IsNil)
+	c.Assert(stdout.String(), Equals, "function 42\n")
+}
+
+func (s *CandySuite) fork(c *C) *Context {
+	snapshot, err := s.ctx.Snapshot()
+	c.Assert(err, IsNil)
+
+	ctx := NewContextFromSnapshot(snapshot)
+	ctx.PushGlobalGoFunction("store", func(value interface{}) {
+		s.stored = value
+	})
+
+	return ctx
+}
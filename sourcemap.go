@@ -0,0 +1,203 @@
+package candyjs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceMap maps the positions of a generated source to the original ones,
+// is used to report the errors at the original file and line.
+type SourceMap struct {
+	// Sources are the original files
+	Sources []string
+	// lines contains the segments of every generated line, sorted by column
+	lines [][]mapping
+}
+
+type mapping struct {
+	column       int
+	source       int
+	sourceLine   int
+	sourceColumn int
+}
+
+// ErrInvalidSourceMap is returned by ParseSourceMap on malformed source maps
+var ErrInvalidSourceMap = errors.New("invalid source map")
+
+// ParseSourceMap parses a source map, version 3, as defined at
+// https://sourcemaps.info/spec.html.
+func ParseSourceMap(data string) (*SourceMap, error) {
+	var raw struct {
+		Version  int
+		Sources  []string
+		Mappings string
+	}
+
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSourceMap, err)
+	}
+
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSourceMap, raw.Version)
+	}
+
+	m := &SourceMap{Sources: raw.Sources}
+
+	// all the fields, but the column, are relative to the previous segment
+	var source, sourceLine, sourceColumn int
+	for line, segments := range strings.Split(raw.Mappings, ";") {
+		var column int
+		for _, segment := range strings.Split(segments, ",") {
+			if segment == "" {
+				continue
+			}
+
+			fields, err := decodeVLQ(segment)
+			if err != nil {
+				return nil, err
+			}
+
+			column += fields[0]
+			if len(fields) < 4 {
+				continue
+			}
+
+			source += fields[1]
+			sourceLine += fields[2]
+			sourceColumn += fields[3]
+			m.add(line, column, source, sourceLine, sourceColumn)
+		}
+	}
+
+	for _, segments := range m.lines {
+		sort.SliceStable(segments, func(i, j int) bool {
+			return segments[i].column < segments[j].column
+		})
+	}
+
+	return m, nil
+}
+
+const vlqChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// decodeVLQ decodes the base64 VLQ values of a segment
+func decodeVLQ(segment string) ([]int, error) {
+	var values []int
+	var value, shift int
+	for _, c := range segment {
+		digit := strings.IndexRune(vlqChars, c)
+		if digit == -1 {
+			return nil, fmt.Errorf("%w: invalid mapping %q", ErrInvalidSourceMap, segment)
+		}
+
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+
+		value, shift = 0, 0
+	}
+
+	if shift != 0 || len(values) == 0 {
+		return nil, fmt.Errorf("%w: invalid mapping %q", ErrInvalidSourceMap, segment)
+	}
+
+	return values, nil
+}
+
+// add adds a new segment, line and column are zero-based, as the source line
+// and column.
+func (m *SourceMap) add(line, column, source, sourceLine, sourceColumn int) {
+	for len(m.lines) <= line {
+		m.lines = append(m.lines, nil)
+	}
+
+	m.lines[line] = append(m.lines[line], mapping{
+		column:       column,
+		source:       source,
+		sourceLine:   sourceLine,
+		sourceColumn: sourceColumn,
+	})
+}
+
+// Position returns the original position of the given one, the lines are
+// one-based, as reported on the errors, and the columns zero-based.
+func (m *SourceMap) Position(line, column int) (source string, sourceLine, sourceColumn int, ok bool) {
+	line--
+	if line < 0 || line >= len(m.lines) || len(m.lines[line]) == 0 {
+		return "", 0, 0, false
+	}
+
+	segments := m.lines[line]
+	i := sort.Search(len(segments), func(i int) bool {
+		return segments[i].column > column
+	})
+
+	if i > 0 {
+		i--
+	}
+
+	s := segments[i]
+	if s.source < 0 || s.source >= len(m.Sources) {
+		return "", 0, 0, false
+	}
+
+	return m.Sources[s.source], s.sourceLine + 1, s.sourceColumn, true
+}
+
+var stackPosition = regexp.MustCompile(`\(([^()]+):(\d+)\)`)
+
+// mapError changes the file and line of the error, and the ones of its stack,
+// to the original ones, if the file has a SourceMap.
+func (ctx *Context) mapError(err *Error) {
+	if len(ctx.sourceMaps) == 0 {
+		return
+	}
+
+	if source, line, ok := ctx.position(err.FileName, err.LineNumber); ok {
+		err.FileName, err.LineNumber = source, line
+	}
+
+	err.Stack = stackPosition.ReplaceAllStringFunc(err.Stack, func(s string) string {
+		match := stackPosition.FindStringSubmatch(s)
+		line, _ := strconv.Atoi(match[2])
+		source, line, ok := ctx.position(match[1], line)
+		if !ok {
+			return s
+		}
+
+		return "(" + source + ":" + strconv.Itoa(line) + ")"
+	})
+}
+
+// position returns the original position of the given line of a file, the
+// SourceMaps of every transformation applied to the file are followed, from
+// the last one.
+func (ctx *Context) position(filename string, line int) (string, int, bool) {
+	maps := ctx.sourceMaps[filename]
+	if len(maps) == 0 {
+		return "", 0, false
+	}
+
+	source := filename
+	for i := len(maps) - 1; i >= 0; i-- {
+		var ok bool
+		if source, line, _, ok = maps[i].Position(line, 0); !ok {
+			return "", 0, false
+		}
+	}
+
+	return source, line, true
+}
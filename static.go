@@ -0,0 +1,116 @@
+package candyjs
+
+import (
+	"errors"
+	"math"
+
+	"github.com/olebedev/go-duktape"
+)
+
+// ErrStaticArgument is returned by a StaticFunction when an argument is not of
+// the expected type, the function is then called with reflection, converting
+// the arguments as done by PushGoFunction.
+var ErrStaticArgument = errors.New("unexpected static function argument")
+
+// StaticFunction reads the arguments of a function call directly from the
+// stack, calls the function and pushes its results without use reflection,
+// returning the number of values pushed, 0 or 1. They are generated by
+// `candyjs import --static` for the functions with basic types as arguments.
+type StaticFunction func(ctx *Context) (int, error)
+
+// StaticType are the types of the arguments read by StaticArg.
+type StaticType interface {
+	bool | string | float32 | float64 |
+		int | int8 | int16 | int32 | int64 |
+		uint | uint8 | uint16 | uint32 | uint64
+}
+
+// PushStaticFunction push the given StaticFunction to the stack as a wrapper
+// of the function f, that is used as reference of the function, like on
+// Inspect or Snapshot. The errors returned are handled as the errors returned
+// by the functions pushed with PushGoFunction, except ErrStaticArgument, that
+// makes call f with reflection.
+//
+// The Contexts created from a Snapshot push f with PushGoFunction, losing the
+// StaticFunction.
+func (ctx *Context) PushStaticFunction(f interface{}, wrapper StaticFunction) int {
+	var reflective func(*duktape.Context) int
+	idx := ctx.pushNativeFunction(func(d *duktape.Context) int {
+		n, err := wrapper(ctx)
+		if err == ErrStaticArgument {
+			if reflective == nil {
+				reflective = ctx.wrapFunction(f)
+			}
+
+			return reflective(d)
+		}
+
+		if err != nil {
+			return duktape.ErrRetError
+		}
+
+		return n
+	})
+
+	ctx.putGoFunctionRef(idx, f)
+	return idx
+}
+
+// StaticArg returns the argument at the given index as a value of T, if it
+// is of the type: the booleans, the strings, converted to UTF-8, and the
+// numbers that fit on T without losing its integer part.
+func StaticArg[T StaticType](ctx *Context, index int) (v T, ok bool) {
+	switch p := any(&v).(type) {
+	case *bool:
+		*p, ok = ctx.GetBoolean(index), ctx.IsBoolean(index)
+	case *string:
+		*p, ok = ctx.getUTF8String(index)
+	case *float64:
+		*p, ok = ctx.getFloat(index)
+	case *float32:
+		var n float64
+		n, ok = ctx.getFloat(index)
+		*p, ok = float32(n), ok && math.Abs(n) <= math.MaxFloat32
+	case *int:
+		*p, ok = staticInteger[int](ctx, index)
+	case *int8:
+		*p, ok = staticInteger[int8](ctx, index)
+	case *int16:
+		*p, ok = staticInteger[int16](ctx, index)
+	case *int32:
+		*p, ok = staticInteger[int32](ctx, index)
+	case *int64:
+		*p, ok = staticInteger[int64](ctx, index)
+	case *uint:
+		*p, ok = staticInteger[uint](ctx, index)
+	case *uint8:
+		*p, ok = staticInteger[uint8](ctx, index)
+	case *uint16:
+		*p, ok = staticInteger[uint16](ctx, index)
+	case *uint32:
+		*p, ok = staticInteger[uint32](ctx, index)
+	case *uint64:
+		*p, ok = staticInteger[uint64](ctx, index)
+	}
+
+	return v, ok
+}
+
+// staticInteger returns the integer at the given index as a T, if it is in
+// the range of T.
+func staticInteger[T int | int8 | int16 | int32 | int64 |
+	uint | uint8 | uint16 | uint32 | uint64](ctx *Context, index int) (T, bool) {
+	n, ok := ctx.getInteger(index)
+	if !ok || n < 0 && T(0)-1 > 0 {
+		return 0, false
+	}
+
+	v := T(n)
+	return v, float64(v) == n
+}
+
+// PushUTF8String push the given UTF-8 string to the stack, as the strings
+// pushed by PushInterface.
+func (ctx *Context) PushUTF8String(s string) {
+	ctx.PushString(utf8ToJS(s))
+}
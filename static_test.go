@@ -0,0 +1,142 @@
+package candyjs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestPushStaticFunction(c *C) {
+	s.ctx.PushGlobalObject()
+	s.ctx.PushStaticFunction(strconv.Itoa, func(ctx *Context) (int, error) {
+		r0 := strconv.Itoa(int(ctx.GetNumber(0)))
+		ctx.PushString(r0)
+		return 1, nil
+	})
+
+	s.ctx.PutPropString(-2, "itoa")
+	s.ctx.Pop()
+
+	c.Assert(s.ctx.PevalString(`store(itoa(42))`), IsNil)
+	c.Assert(s.stored, Equals, "42")
+
+	c.Assert(s.ctx.PevalString(`itoa`), IsNil)
+	c.Assert(s.ctx.Inspect(-1), Equals, "[Go Function: func(int) string]")
+}
+
+func (s *CandySuite) TestPushStaticFunction_Error(c *C) {
+	s.ctx.PushGlobalObject()
+	s.ctx.PushStaticFunction(nil, func(ctx *Context) (int, error) {
+		return 0, errors.New("foo")
+	})
+
+	s.ctx.PutPropString(-2, "test")
+	s.ctx.Pop()
+
+	c.Assert(s.ctx.PevalString(`
+		try { test(); } catch(err) { store(true); }
+	`), IsNil)
+
+	c.Assert(s.stored, Equals, true)
+}
+
+func (s *CandySuite) TestPushStaticFunction_Fallback(c *C) {
+	var static int
+	s.ctx.PushGlobalObject()
+	s.ctx.PushStaticFunction(strings.Repeat, func(ctx *Context) (int, error) {
+		a0, ok0 := StaticArg[string](ctx, 0)
+		a1, ok1 := StaticArg[int](ctx, 1)
+		if !ok0 || !ok1 {
+			return 0, ErrStaticArgument
+		}
+
+		static++
+		ctx.PushUTF8String(strings.Repeat(a0, a1))
+		return 1, nil
+	})
+
+	s.ctx.PutPropString(-2, "repeat")
+	s.ctx.Pop()
+
+	c.Assert(s.ctx.PevalString(`
+		store([repeat("😀", 2) == "😀😀", repeat(new String("a"), 2), repeat("a")].join())
+	`), IsNil)
+	c.Assert(s.stored, Equals, "true,aa,")
+	c.Assert(static, Equals, 1)
+}
+
+func (s *CandySuite) TestStaticArg(c *C) {
+	c.Assert(s.ctx.PevalString(`[true, "a😀", 1.5, 300, -1, 1e40, NaN]`), IsNil)
+
+	for i, expected := range []interface{}{true, "a😀", 1.5, 300, int64(-1), float64(1e40)} {
+		s.ctx.GetPropIndex(-1, uint(i))
+		var v interface{}
+		var ok bool
+		switch expected.(type) {
+		case bool:
+			v, ok = StaticArg[bool](s.ctx, -1)
+		case string:
+			v, ok = StaticArg[string](s.ctx, -1)
+		case float64:
+			v, ok = StaticArg[float64](s.ctx, -1)
+		case int:
+			v, ok = StaticArg[int](s.ctx, -1)
+		case int64:
+			v, ok = StaticArg[int64](s.ctx, -1)
+		}
+
+		c.Assert(ok, Equals, true, Commentf("%d", i))
+		c.Assert(v, Equals, expected)
+		s.ctx.Pop()
+	}
+
+	for i, f := range map[int]func() bool{
+		0: func() bool { _, ok := StaticArg[string](s.ctx, -1); return ok },
+		2: func() bool { _, ok := StaticArg[int](s.ctx, -1); return ok },
+		3: func() bool { _, ok := StaticArg[uint8](s.ctx, -1); return ok },
+		4: func() bool { _, ok := StaticArg[uint](s.ctx, -1); return ok },
+		5: func() bool { _, ok := StaticArg[float32](s.ctx, -1); return ok },
+		6: func() bool { _, ok := StaticArg[float64](s.ctx, -1); return ok },
+	} {
+		s.ctx.GetPropIndex(-1, uint(i))
+		c.Assert(f(), Equals, false, Commentf("%d", i))
+		s.ctx.Pop()
+	}
+}
+
+func (s *CandySuite) BenchmarkStaticFunctionCall(c *C) {
+	s.ctx.PushGlobalObject()
+	s.ctx.PushStaticFunction(benchmarkFunction, func(ctx *Context) (int, error) {
+		r0 := benchmarkFunction(int(ctx.GetNumber(0)), int(ctx.GetNumber(1)))
+		ctx.PushInt(r0)
+		return 1, nil
+	})
+
+	s.ctx.PutPropString(-2, "test")
+	s.ctx.Pop()
+
+	s.benchmarkFunctionCall(c)
+}
+
+func (s *CandySuite) BenchmarkReflectFunctionCall(c *C) {
+	s.ctx.PushGlobalGoFunction("test", benchmarkFunction)
+	s.benchmarkFunctionCall(c)
+}
+
+func (s *CandySuite) benchmarkFunctionCall(c *C) {
+	s.ctx.PushGlobalInterface("n", c.N)
+
+	c.ResetTimer()
+	c.Assert(s.ctx.PevalString(`
+		var r = 0;
+		for (var i = 0; i < n; i++) {
+			r = test(i, 2);
+		}
+	`), IsNil)
+}
+
+func benchmarkFunction(a, b int) int {
+	return a * b
+}
@@ -1,19 +1,24 @@
 package candyjs
 
+// #include <stdlib.h>
 import "C"
 import (
+	"io"
 	"sync"
 	"unsafe"
 )
 
 type storage struct {
 	vars map[unsafe.Pointer]interface{}
+	// closers are closed when its value is removed, being owned by the Context
+	closers map[unsafe.Pointer]io.Closer
 	sync.Mutex
 }
 
 func newStorage() *storage {
 	return &storage{
-		vars: make(map[unsafe.Pointer]interface{}, 0),
+		vars:    make(map[unsafe.Pointer]interface{}, 0),
+		closers: make(map[unsafe.Pointer]io.Closer, 0),
 	}
 }
 
@@ -27,9 +32,63 @@ func (s *storage) add(v interface{}) unsafe.Pointer {
 	return ptr
 }
 
+// addCloser like add, but the value is closed when is removed.
+func (s *storage) addCloser(c io.Closer) unsafe.Pointer {
+	ptr := s.add(c)
+
+	s.Lock()
+	s.closers[ptr] = c
+	s.Unlock()
+
+	return ptr
+}
+
 func (s *storage) get(ptr unsafe.Pointer) interface{} {
 	s.Lock()
 	defer s.Unlock()
 
 	return s.vars[ptr]
 }
+
+// delete removes the value and frees the pointer.
+func (s *storage) delete(ptr unsafe.Pointer) {
+	s.Lock()
+	if _, ok := s.vars[ptr]; !ok {
+		s.Unlock()
+		return
+	}
+
+	c := s.closers[ptr]
+	delete(s.vars, ptr)
+	delete(s.closers, ptr)
+	C.free(ptr)
+	s.Unlock()
+
+	if c != nil {
+		c.Close()
+	}
+}
+
+// destroy removes all the values and frees its pointers.
+func (s *storage) destroy() {
+	s.Lock()
+	for ptr := range s.vars {
+		C.free(ptr)
+	}
+
+	closers := s.closers
+	s.vars = make(map[unsafe.Pointer]interface{}, 0)
+	s.closers = make(map[unsafe.Pointer]io.Closer, 0)
+	s.Unlock()
+
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+func (s *storage) len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.vars)
+}
@@ -0,0 +1,204 @@
+package candyjs
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrInvalidWriter is returned by the pipe method of the streams when the
+// destination is not a io.Writer nor an object with a write method.
+var ErrInvalidWriter = errors.New("invalid writer")
+
+// streamChunkSize is the default size of read and the size of the chunks
+// given to the write method of the JavaScript writers by pipe.
+const streamChunkSize = 32 * 1024
+
+// stream adds to the proxies of the io.Reader, io.Writer and io.Closer values
+// the following methods, replacing the Go ones with the same name:
+//  - read(n): returns a buffer with up to n bytes, or null at the end
+//  - readLine(): returns the next line, without the line break, or null at
+//    the end
+//  - pipe(writer): copies the whole reader, in chunks, to a io.Writer or to
+//    an object with a write method, returning the number of bytes copied
+//  - write(data): writes a buffer or a string, returning the bytes written
+//  - close(): closes the value
+type stream struct {
+	v  interface{}
+	r  io.Reader
+	br *bufio.Reader
+}
+
+func newStream(v interface{}) *stream {
+	switch v.(type) {
+	case io.Reader, io.Writer, io.Closer:
+	default:
+		return nil
+	}
+
+	s := &stream{v: v}
+	s.r, _ = v.(io.Reader)
+
+	return s
+}
+
+func (s *stream) has(t interface{}, k string) bool {
+	return s.method(k) != nil || p.has(t, k)
+}
+
+func (s *stream) get(t interface{}, k string, recv interface{}) (interface{}, error) {
+	if f := s.method(k); f != nil {
+		return f, nil
+	}
+
+	return p.get(t, k, recv)
+}
+
+func (s *stream) method(k string) StaticFunction {
+	_, isWriter := s.v.(io.Writer)
+	_, isCloser := s.v.(io.Closer)
+
+	switch {
+	case k == "read" && s.r != nil:
+		return s.read
+	case k == "readLine" && s.r != nil:
+		return s.readLine
+	case k == "pipe" && s.r != nil:
+		return s.pipe
+	case k == "write" && isWriter:
+		return s.write
+	case k == "close" && isCloser:
+		return s.close
+	}
+
+	return nil
+}
+
+// reader returns the reader to use, once readLine is called the reads are
+// done from its buffered reader, to not lose the data buffered.
+func (s *stream) reader() io.Reader {
+	if s.br != nil {
+		return s.br
+	}
+
+	return s.r
+}
+
+func (s *stream) read(ctx *Context) (int, error) {
+	size := streamChunkSize
+	if ctx.IsNumber(0) && ctx.GetInt(0) > 0 {
+		size = ctx.GetInt(0)
+	}
+
+	buf := make([]byte, size)
+	n, err := io.ReadAtLeast(s.reader(), buf, 1)
+	if err == io.EOF {
+		ctx.PushNull()
+		return 1, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	ctx.PushBytes(buf[:n])
+	return 1, nil
+}
+
+func (s *stream) readLine(ctx *Context) (int, error) {
+	if s.br == nil {
+		s.br = bufio.NewReader(s.r)
+	}
+
+	line, err := s.br.ReadString('\n')
+	if err == io.EOF && line == "" {
+		ctx.PushNull()
+		return 1, nil
+	}
+
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	ctx.PushString(strings.TrimSuffix(line, "\r"))
+	return 1, nil
+}
+
+func (s *stream) pipe(ctx *Context) (int, error) {
+	var n int64
+	var err error
+	if w, ok := ctx.getProxy(0).(io.Writer); ok {
+		n, err = io.Copy(w, s.reader())
+	} else if ctx.IsObject(0) && ctx.hasFunction(0, "write") {
+		n, err = s.pipeObject(ctx, 0)
+	} else {
+		err = ErrInvalidWriter
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	ctx.PushNumber(float64(n))
+	return 1, nil
+}
+
+// pipeObject copies the reader calling the write method of the object at the
+// given index with every chunk.
+func (s *stream) pipeObject(ctx *Context, index int) (int64, error) {
+	var n int64
+	buf := make([]byte, streamChunkSize)
+	for {
+		read, err := s.reader().Read(buf)
+		if read > 0 {
+			ctx.PushString("write")
+			ctx.PushBytes(buf[:read])
+			if ctx.PcallProp(index, 1) != 0 {
+				defer ctx.Pop()
+				return n, ctx.getError(-1)
+			}
+
+			ctx.Pop()
+			n += int64(read)
+		}
+
+		if err == io.EOF {
+			return n, nil
+		}
+
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+func (s *stream) write(ctx *Context) (int, error) {
+	ctx.SetTop(1)
+	data, ok := ctx.getBuffer(0)
+	if !ok {
+		data = []byte(ctx.SafeToString(0))
+	}
+
+	n, err := s.v.(io.Writer).Write(data)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx.PushInt(n)
+	return 1, nil
+}
+
+func (s *stream) close(ctx *Context) (int, error) {
+	return 0, s.v.(io.Closer).Close()
+}
+
+// hasFunction returns if the object at the given index has a function at the
+// given property.
+func (ctx *Context) hasFunction(index int, name string) bool {
+	defer ctx.Pop()
+	ctx.GetPropString(index, name)
+
+	return ctx.IsFunction(-1)
+}
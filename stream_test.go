@@ -0,0 +1,88 @@
+package candyjs
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type closerRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closerRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (s *CandySuite) TestStream_Read(c *C) {
+	s.ctx.PushGlobalProxy("r", strings.NewReader("foobar"))
+	c.Assert(s.ctx.PevalString(`
+		var chunks = [], chunk;
+		while ((chunk = r.read(4)) !== null) {
+			chunks.push(chunk.length + ':' + String.fromCharCode.apply(null, Array.prototype.slice.call(chunk)));
+		}
+
+		chunks.join(',')
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "4:foob,2:ar")
+}
+
+func (s *CandySuite) TestStream_ReadLine(c *C) {
+	s.ctx.PushGlobalProxy("r", strings.NewReader("foo\r\nbar\nqux"))
+	c.Assert(s.ctx.PevalString(`
+		var lines = [r.readLine()], line;
+		var chunk = r.read(2);
+		while ((line = r.readLine()) !== null) {
+			lines.push(line);
+		}
+
+		lines.concat(chunk.length).join(',')
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "foo,r,qux,2")
+}
+
+func (s *CandySuite) TestStream_Write(c *C) {
+	buf := bytes.NewBuffer(nil)
+	s.ctx.PushGlobalProxy("w", buf)
+	s.ctx.PushGlobalProxy("r", strings.NewReader("bar"))
+	c.Assert(s.ctx.PevalString(`w.write('foo') + w.write(r.read())`), IsNil)
+
+	c.Assert(s.ctx.GetNumber(-1), Equals, 6.0)
+	c.Assert(buf.String(), Equals, "foobar")
+}
+
+func (s *CandySuite) TestStream_Pipe(c *C) {
+	buf := bytes.NewBuffer(nil)
+	s.ctx.PushGlobalProxy("w", buf)
+	s.ctx.PushGlobalProxy("r", strings.NewReader(strings.Repeat("x", streamChunkSize+1)))
+	c.Assert(s.ctx.PevalString(`r.pipe(w)`), IsNil)
+
+	c.Assert(s.ctx.GetNumber(-1), Equals, float64(streamChunkSize+1))
+	c.Assert(buf.Len(), Equals, streamChunkSize+1)
+}
+
+func (s *CandySuite) TestStream_PipeObject(c *C) {
+	s.ctx.PushGlobalProxy("r", strings.NewReader(strings.Repeat("x", streamChunkSize+1)))
+	c.Assert(s.ctx.PevalString(`
+		var sizes = [];
+		r.pipe({write: function(chunk) { sizes.push(chunk.length) }});
+		sizes.join(',')
+	`), IsNil)
+
+	c.Assert(s.ctx.GetString(-1), Equals, "32768,1")
+	c.Assert(s.ctx.PevalString(`r.pipe({})`), NotNil)
+}
+
+func (s *CandySuite) TestStream_Close(c *C) {
+	r := &closerRecorder{Reader: strings.NewReader("foo")}
+	s.ctx.PushGlobalProxy("r", r)
+	c.Assert(s.ctx.PevalString(`r.close()`), IsNil)
+
+	c.Assert(r.closed, Equals, true)
+}
@@ -0,0 +1,196 @@
+package candyjs
+
+import (
+	"container/list"
+	"sync"
+)
+
+// evalFileName is the file name given by Duktape to the evaluated strings
+const evalFileName = "eval"
+
+// Transformer transforms the source of the scripts before they are compiled,
+// like a transpiler from newer versions of JavaScript to the ES5 supported by
+// Duktape. A Transformer should be safe for concurrent use, since can be
+// shared between Contexts.
+type Transformer interface {
+	// Transform returns the transformed source of the given file and its
+	// source map, version 3. The source map can be empty if the lines of the
+	// source are not altered.
+	Transform(filename, src string) (code, sourceMap string, err error)
+}
+
+// DefaultTransformCacheEntries is the maximum number of results kept by a
+// TransformCache when MaxEntries is zero.
+const DefaultTransformCacheEntries = 1024
+
+// TransformCache caches the output of a Transformer in memory, keyed by the
+// hash of the filename and the source. When MaxEntries is reached the least
+// recently used results are evicted.
+type TransformCache struct {
+	// MaxEntries is the maximum number of results kept, if zero
+	// DefaultTransformCacheEntries is used.
+	MaxEntries int
+
+	t       Transformer
+	results map[string]*list.Element
+	lru     *list.List
+	sync.Mutex
+}
+
+type transformResult struct {
+	key             string
+	code, sourceMap string
+	err             error
+}
+
+// NewTransformCache returns a new TransformCache of the given Transformer.
+func NewTransformCache(t Transformer) *TransformCache {
+	return &TransformCache{
+		t:       t,
+		results: make(map[string]*list.Element, 0),
+		lru:     list.New(),
+	}
+}
+
+// Transform follows the Transformer interface, the source is transformed only
+// the first time is given, while is cached.
+func (c *TransformCache) Transform(filename, src string) (string, string, error) {
+	key := scriptKey("", filename, src)
+
+	r := c.get(key)
+	if r == nil {
+		r = &transformResult{key: key}
+		r.code, r.sourceMap, r.err = c.t.Transform(filename, src)
+		c.add(r)
+	}
+
+	return r.code, r.sourceMap, r.err
+}
+
+func (c *TransformCache) get(key string) *transformResult {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.results[key]
+	if !ok {
+		return nil
+	}
+
+	c.lru.MoveToFront(e)
+	return e.Value.(*transformResult)
+}
+
+func (c *TransformCache) add(r *transformResult) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.results[r.key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.results[r.key] = c.lru.PushFront(r)
+
+	max := c.MaxEntries
+	if max <= 0 {
+		max = DefaultTransformCacheEntries
+	}
+
+	for c.lru.Len() > max {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.results, e.Value.(*transformResult).key)
+	}
+}
+
+func (c *TransformCache) len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.lru.Len()
+}
+
+// PevalString like the duktape.Context.PevalString but the source is
+// transformed by the Transformer given at the Options, if any.
+func (ctx *Context) PevalString(src string) error {
+	if ctx.transformer == nil {
+		return ctx.Context.PevalString(src)
+	}
+
+	src, err := ctx.transform(evalFileName, src)
+	if err != nil {
+		ctx.pushError(err)
+		return err
+	}
+
+	return ctx.Context.PevalString(src)
+}
+
+// EvalString like PevalString but panics on error.
+func (ctx *Context) EvalString(src string) {
+	if ctx.transformer == nil {
+		ctx.Context.EvalString(src)
+		return
+	}
+
+	if err := ctx.PevalString(src); err != nil {
+		ctx.Pop()
+		panic(err)
+	}
+}
+
+// transform applies to the source of the given script the Transformer and
+// the transformations enabled at the Options, the SourceMaps are stored to
+// map the errors to the original files.
+func (ctx *Context) transform(filename, src string) (string, error) {
+	return ctx.transformSource(filename, src, false)
+}
+
+// transformModule like transform, but for the modules loaded by require, the
+// only ones where the export statements are allowed.
+func (ctx *Context) transformModule(filename, src string) (string, error) {
+	return ctx.transformSource(filename, src, true)
+}
+
+func (ctx *Context) transformSource(filename, src string, module bool) (string, error) {
+	var maps []*SourceMap
+	if ctx.transformer != nil {
+		code, sourceMap, err := ctx.transformer.Transform(filename, src)
+		if err != nil {
+			return "", err
+		}
+
+		if sourceMap != "" {
+			m, err := ParseSourceMap(sourceMap)
+			if err != nil {
+				return "", err
+			}
+
+			maps = append(maps, m)
+		}
+
+		src = code
+	}
+
+	if ctx.esModules && filename != evalFileName {
+		code, m, err := transformESModule(filename, src, module)
+		if err != nil {
+			return "", err
+		}
+
+		maps = append(maps, m)
+		src = code
+	}
+
+	if ctx.sourceMaps == nil {
+		ctx.sourceMaps = make(map[string][]*SourceMap, 0)
+	}
+
+	if len(maps) == 0 {
+		delete(ctx.sourceMaps, filename)
+	} else {
+		ctx.sourceMaps[filename] = maps
+	}
+
+	return src, nil
+}
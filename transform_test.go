@@ -0,0 +1,130 @@
+package candyjs
+
+import (
+	"strings"
+	"testing/fstest"
+
+	. "gopkg.in/check.v1"
+)
+
+// headerTransformer replaces `let` by `var` and adds a header of two lines
+type headerTransformer struct {
+	calls int
+}
+
+func (t *headerTransformer) Transform(filename, src string) (string, string, error) {
+	t.calls++
+	if strings.Contains(src, "@invalid") {
+		return "", "", &Error{Type: "SyntaxError", Message: "invalid", FileName: filename, LineNumber: 1}
+	}
+
+	mappings := ";;AAAA" + strings.Repeat(";AACA", strings.Count(src, "\n"))
+	sourceMap := `{"version": 3, "sources": ["` + filename + `"], "mappings": "` + mappings + `"}`
+
+	return "// generated\n// header\n" + strings.Replace(src, "let ", "var ", -1), sourceMap, nil
+}
+
+func (s *CandySuite) TestTransformer(c *C) {
+	t := &headerTransformer{}
+	ctx := NewContextWithOptions(Options{
+		Transformer: t,
+		FS: fstest.MapFS{
+			"main.js":  {Data: []byte("let foo = require('./foo');\nfoo.bar")},
+			"foo.js":   {Data: []byte("let bar = 'qux';\nexports.bar = bar;")},
+			"error.js": {Data: []byte("let foo;\n\nthrow new Error('foo');")},
+		},
+	})
+	defer ctx.DestroyHeap()
+
+	c.Assert(ctx.PevalString(`let x = 42; x`), IsNil)
+	c.Assert(ctx.GetNumber(-1), Equals, 42.0)
+
+	c.Assert(ctx.PevalFile("main.js"), IsNil)
+	c.Assert(ctx.GetString(-1), Equals, "qux")
+
+	err := ctx.PevalFile("error.js")
+	c.Assert(err, FitsTypeOf, &Error{})
+	c.Assert(err.(*Error).FileName, Equals, "error.js")
+	c.Assert(err.(*Error).LineNumber, Equals, 3)
+	c.Assert(err.(*Error).Stack, Matches, "(?s).*\\(error.js:3\\).*")
+
+	c.Assert(t.calls, Equals, 4)
+}
+
+func (s *CandySuite) TestTransformer_Error(c *C) {
+	ctx := NewContextWithOptions(Options{Transformer: &headerTransformer{}})
+	defer ctx.DestroyHeap()
+
+	err := ctx.PevalString(`// @invalid`)
+	c.Assert(err, ErrorMatches, "SyntaxError: invalid \\(eval:1\\)")
+	c.Assert(ctx.IsError(-1), Equals, true)
+}
+
+func (s *CandySuite) TestTransformer_ESModules(c *C) {
+	ctx := NewContextWithOptions(Options{
+		Transformer: &headerTransformer{},
+		ESModules:   true,
+		FS: fstest.MapFS{
+			"foo.js": {Data: []byte("export let foo = 'bar';\n\nthrow new Error('foo');")},
+		},
+	})
+	defer ctx.DestroyHeap()
+
+	ctx.PevalString(`require('./foo')`)
+	err := ctx.getError(-1)
+	c.Assert(err.FileName, Equals, "foo.js")
+	c.Assert(err.LineNumber, Equals, 3)
+}
+
+func (s *CandySuite) TestTransformCache(c *C) {
+	t := &headerTransformer{}
+	cache := NewTransformCache(t)
+
+	for i := 0; i < 2; i++ {
+		ctx := NewContextWithOptions(Options{Transformer: cache})
+		c.Assert(ctx.PevalString(`let x = 42; x`), IsNil)
+		c.Assert(ctx.GetNumber(-1), Equals, 42.0)
+		ctx.DestroyHeap()
+	}
+
+	c.Assert(t.calls, Equals, 1)
+}
+
+func (s *CandySuite) TestTransformCache_MaxEntries(c *C) {
+	t := &headerTransformer{}
+	cache := NewTransformCache(t)
+	cache.MaxEntries = 2
+
+	for _, src := range []string{"foo", "bar", "foo", "qux", "foo", "bar"} {
+		_, _, err := cache.Transform("foo.js", src)
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(t.calls, Equals, 4)
+	c.Assert(cache.len(), Equals, 2)
+}
+
+func (s *CandySuite) TestParseSourceMap(c *C) {
+	m, err := ParseSourceMap(`{
+		"version": 3,
+		"sources": ["foo.js", "bar.js"],
+		"mappings": "AAAA,IAAI;;ECCF,KAAK"
+	}`)
+	c.Assert(err, IsNil)
+
+	source, line, column, ok := m.Position(1, 5)
+	c.Assert(ok, Equals, true)
+	c.Assert(source, Equals, "foo.js")
+	c.Assert([]int{line, column}, DeepEquals, []int{1, 4})
+
+	_, _, _, ok = m.Position(2, 0)
+	c.Assert(ok, Equals, false)
+
+	source, line, column, ok = m.Position(3, 6)
+	c.Assert(ok, Equals, true)
+	c.Assert(source, Equals, "bar.js")
+	c.Assert([]int{line, column}, DeepEquals, []int{2, 2})
+
+	_, err = ParseSourceMap(`{"version": 3, "mappings": "A!"}`)
+	c.Assert(err, ErrorMatches, "invalid source map: .*")
+}
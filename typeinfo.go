@@ -0,0 +1,404 @@
+package candyjs
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sync"
+)
+
+var types = newTypeCache()
+
+// typeCache holds the reflection metadata of every Go type that reached a
+// Context, it is shared by all the Contexts and every type is computed once.
+type typeCache struct {
+	types map[reflect.Type]*typeInfo
+	sync.RWMutex
+}
+
+func newTypeCache() *typeCache {
+	return &typeCache{
+		types: make(map[reflect.Type]*typeInfo, 0),
+	}
+}
+
+func (c *typeCache) get(t reflect.Type) *typeInfo {
+	c.RLock()
+	info, ok := c.types[t]
+	c.RUnlock()
+	if ok {
+		return info
+	}
+
+	info = newTypeInfo(t)
+
+	c.Lock()
+	defer c.Unlock()
+	if cached, ok := c.types[t]; ok {
+		return cached
+	}
+
+	c.types[t] = info
+	return info
+}
+
+// typeInfo contains the field indexes, method indexes and JavaScript names
+// of a type, the converter of its values, plus the signature of the type when
+// is a function.
+type typeInfo struct {
+	fieldsByName  map[string][]int
+	fieldsByKey   map[string][]int
+	methodsByName map[string]int
+	methodsByKey  map[string]int
+	names         []string
+	convert       converter
+	fn            *funcInfo
+}
+
+func newTypeInfo(t reflect.Type) *typeInfo {
+	i := &typeInfo{
+		fieldsByName:  make(map[string][]int, 0),
+		fieldsByKey:   make(map[string][]int, 0),
+		methodsByName: make(map[string]int, 0),
+		methodsByKey:  make(map[string]int, 0),
+		convert:       newConverter(t),
+	}
+
+	i.loadFields(t)
+	i.loadMethods(t)
+	i.loadNames(t)
+
+	if t.Kind() == reflect.Func {
+		i.fn = newFuncInfo(t)
+	}
+
+	return i
+}
+
+func (i *typeInfo) loadFields(t reflect.Type) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, f := range reflect.VisibleFields(t) {
+		if !isExported(f.Name) {
+			continue
+		}
+
+		// FieldByName resolves the ambiguous and shadowed fields
+		if sf, ok := t.FieldByName(f.Name); ok {
+			i.fieldsByName[f.Name] = sf.Index
+		}
+	}
+
+	for name := range i.fieldsByName {
+		key := nameToJavaScript(name)
+		if index, found := i.lookupField(key); found {
+			i.fieldsByKey[key] = index
+		}
+	}
+}
+
+func (i *typeInfo) loadMethods(t reflect.Type) {
+	mCount := t.NumMethod()
+	for m := 0; m < mCount; m++ {
+		name := t.Method(m).Name
+		if !isExported(name) {
+			continue
+		}
+
+		i.methodsByName[name] = m
+	}
+
+	for name := range i.methodsByName {
+		key := nameToJavaScript(name)
+		if index, found := i.lookupMethod(key); found {
+			i.methodsByKey[key] = index
+		}
+	}
+}
+
+func (i *typeInfo) loadNames(t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		i.names = append(i.names, types.get(t.Elem()).names...)
+	case reflect.Struct:
+		fCount := t.NumField()
+		for f := 0; f < fCount; f++ {
+			fieldName := t.Field(f).Name
+			if !isExported(fieldName) {
+				continue
+			}
+
+			i.names = append(i.names, nameToJavaScript(fieldName))
+		}
+	}
+
+	mCount := t.NumMethod()
+	for m := 0; m < mCount; m++ {
+		methodName := t.Method(m).Name
+		if !isExported(methodName) {
+			continue
+		}
+
+		i.names = append(i.names, nameToJavaScript(methodName))
+	}
+}
+
+// field returns the index of the field matching the given JavaScript key, as
+// required by reflect.Value.FieldByIndex
+func (i *typeInfo) field(key string) ([]int, bool) {
+	if index, ok := i.fieldsByKey[key]; ok {
+		return index, true
+	}
+
+	return i.lookupField(key)
+}
+
+func (i *typeInfo) lookupField(key string) ([]int, bool) {
+	for _, name := range nameToGo(key) {
+		if index, ok := i.fieldsByName[name]; ok {
+			return index, true
+		}
+	}
+
+	return nil, false
+}
+
+// method returns the index of the method matching the given JavaScript key, as
+// required by reflect.Value.Method
+func (i *typeInfo) method(key string) (int, bool) {
+	if index, ok := i.methodsByKey[key]; ok {
+		return index, true
+	}
+
+	return i.lookupMethod(key)
+}
+
+func (i *typeInfo) lookupMethod(key string) (int, bool) {
+	for _, name := range nameToGo(key) {
+		if index, ok := i.methodsByName[name]; ok {
+			return index, true
+		}
+	}
+
+	return -1, false
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// funcInfo is the signature of a function already digested, avoiding walk the
+// reflect.Type on every call.
+type funcInfo struct {
+	in          []reflect.Type
+	out         []reflect.Type
+	isVariadic  bool
+	returnError bool
+	// conv and outConv are the converters of the arguments and the results,
+	// variadic is the one of the variadic elements
+	conv     []converter
+	outConv  []converter
+	variadic converter
+}
+
+func newFuncInfo(t reflect.Type) *funcInfo {
+	f := &funcInfo{isVariadic: t.IsVariadic()}
+
+	inCount := t.NumIn()
+	for i := 0; i < inCount; i++ {
+		f.in = append(f.in, t.In(i))
+		f.conv = append(f.conv, newConverter(t.In(i)))
+	}
+
+	if f.isVariadic {
+		f.variadic = newConverter(t.In(inCount - 1).Elem())
+	}
+
+	oCount := t.NumOut()
+	for i := 0; i < oCount; i++ {
+		f.out = append(f.out, t.Out(i))
+		f.outConv = append(f.outConv, newConverter(t.Out(i)))
+	}
+
+	f.returnError = oCount > 0 && t.Out(oCount-1) == errorType
+	return f
+}
+
+// argType returns the type of the argument at the given position, the extra
+// arguments of a variadic function get the type of the variadic elements. Nil
+// is returned when the function does not accept an argument at that position.
+func (f *funcInfo) argType(index int) reflect.Type {
+	inCount := len(f.in)
+	if (index+1) < inCount || (index < inCount && !f.isVariadic) {
+		return f.in[index]
+	}
+
+	if f.isVariadic {
+		return f.in[inCount-1].Elem()
+	}
+
+	return nil
+}
+
+// argConverter returns the converter of the argument at the given position,
+// following the same rules as argType.
+func (f *funcInfo) argConverter(index int) converter {
+	inCount := len(f.in)
+	if (index+1) < inCount || (index < inCount && !f.isVariadic) {
+		return f.conv[index]
+	}
+
+	if f.isVariadic {
+		return f.variadic
+	}
+
+	return genericConverter(nil)
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// converter returns the value at the given index of the stack as a value of a
+// Go type, they are built once per type by newConverter.
+type converter func(ctx *Context, index int) reflect.Value
+
+// newConverter returns the converter of the given type. The booleans, numbers
+// and strings, converted to UTF-8, are read directly from the stack when they fit on
+// the type, any other value, or types implementing json.Unmarshaler, follow
+// the rules of getValueFromContext.
+func newConverter(t reflect.Type) converter {
+	generic := genericConverter(t)
+	if t == nil || reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		return generic
+	}
+
+	var read func(ctx *Context, index int, v reflect.Value) bool
+	switch t.Kind() {
+	case reflect.Bool:
+		read = func(ctx *Context, index int, v reflect.Value) bool {
+			if !ctx.IsBoolean(index) {
+				return false
+			}
+
+			v.SetBool(ctx.GetBoolean(index))
+			return true
+		}
+	case reflect.String:
+		read = func(ctx *Context, index int, v reflect.Value) bool {
+			s, ok := ctx.getUTF8String(index)
+			if !ok {
+				return false
+			}
+
+			v.SetString(s)
+			return true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		read = func(ctx *Context, index int, v reflect.Value) bool {
+			n, ok := ctx.getInteger(index)
+			if !ok || v.OverflowInt(int64(n)) {
+				return false
+			}
+
+			v.SetInt(int64(n))
+			return true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		read = func(ctx *Context, index int, v reflect.Value) bool {
+			n, ok := ctx.getInteger(index)
+			if !ok || n < 0 || v.OverflowUint(uint64(n)) {
+				return false
+			}
+
+			v.SetUint(uint64(n))
+			return true
+		}
+	case reflect.Float32, reflect.Float64:
+		read = func(ctx *Context, index int, v reflect.Value) bool {
+			n, ok := ctx.getFloat(index)
+			if !ok || v.OverflowFloat(n) {
+				return false
+			}
+
+			v.SetFloat(n)
+			return true
+		}
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return generic
+		}
+
+		read = func(ctx *Context, index int, v reflect.Value) bool {
+			s, isString := ctx.getUTF8String(index)
+			switch {
+			case ctx.IsBoolean(index):
+				v.Set(reflect.ValueOf(ctx.GetBoolean(index)))
+			case isString:
+				v.Set(reflect.ValueOf(s))
+			case ctx.IsNumber(index) && !math.IsNaN(ctx.GetNumber(index)) &&
+				!math.IsInf(ctx.GetNumber(index), 0):
+				v.Set(reflect.ValueOf(ctx.GetNumber(index)))
+			default:
+				return false
+			}
+
+			return true
+		}
+	default:
+		return generic
+	}
+
+	return func(ctx *Context, index int) reflect.Value {
+		v := reflect.New(t).Elem()
+		if !read(ctx, index, v) {
+			return generic(ctx, index)
+		}
+
+		return v
+	}
+}
+
+func genericConverter(t reflect.Type) converter {
+	return func(ctx *Context, index int) reflect.Value {
+		return ctx.getValueFromContext(index, t)
+	}
+}
+
+// getUTF8String returns the string at the given index converted to UTF-8 by
+// jsToUTF8, the symbols are not read as strings.
+func (ctx *Context) getUTF8String(index int) (string, bool) {
+	if !ctx.IsString(index) {
+		return "", false
+	}
+
+	s := ctx.GetString(index)
+	if s != "" && isSymbol(s) {
+		return "", false
+	}
+
+	return jsToUTF8(s), true
+}
+
+// getFloat returns the number at the given index if is finite.
+func (ctx *Context) getFloat(index int) (float64, bool) {
+	if !ctx.IsNumber(index) {
+		return 0, false
+	}
+
+	n := ctx.GetNumber(index)
+	return n, !math.IsNaN(n) && !math.IsInf(n, 0)
+}
+
+// getInteger returns the number at the given index if is an integer exactly
+// representable as a float64.
+func (ctx *Context) getInteger(index int) (float64, bool) {
+	if !ctx.IsNumber(index) {
+		return 0, false
+	}
+
+	n := ctx.GetNumber(index)
+	if n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+		return 0, false
+	}
+
+	return n, true
+}
@@ -0,0 +1,157 @@
+package candyjs
+
+import (
+	"fmt"
+	"reflect"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *CandySuite) TestTypeCache_Get(c *C) {
+	t := reflect.TypeOf(&MyStruct{})
+	c.Assert(types.get(t), Equals, types.get(t))
+}
+
+func (s *CandySuite) TestTypeInfo_Field(c *C) {
+	info := types.get(reflect.TypeOf(MyStruct{}))
+
+	index, found := info.field("uInt8")
+	c.Assert(found, Equals, true)
+	c.Assert(index, DeepEquals, []int{7})
+
+	_, found = info.field("private")
+	c.Assert(found, Equals, false)
+
+	_, found = info.field("foo")
+	c.Assert(found, Equals, false)
+}
+
+func (s *CandySuite) TestTypeInfo_FieldEmbedded(c *C) {
+	info := types.get(reflect.TypeOf(embeddedStruct{}))
+
+	index, found := info.field("int")
+	c.Assert(found, Equals, true)
+	c.Assert(index, DeepEquals, []int{0, 1})
+
+	index, found = info.field("qux")
+	c.Assert(found, Equals, true)
+	c.Assert(index, DeepEquals, []int{1})
+}
+
+func (s *CandySuite) TestTypeInfo_Method(c *C) {
+	info := types.get(reflect.TypeOf(&MyStruct{}))
+
+	_, found := info.method("multiply")
+	c.Assert(found, Equals, true)
+
+	_, found = info.method("privateMethod")
+	c.Assert(found, Equals, false)
+
+	_, found = types.get(reflect.TypeOf(MyStruct{})).method("multiply")
+	c.Assert(found, Equals, false)
+}
+
+func (s *CandySuite) TestTypeInfo_Fn(c *C) {
+	info := types.get(reflect.TypeOf(func(string, ...int) (int, error) {
+		return 0, nil
+	}))
+
+	c.Assert(info.fn.isVariadic, Equals, true)
+	c.Assert(info.fn.returnError, Equals, true)
+	c.Assert(info.fn.argType(0).Kind(), Equals, reflect.String)
+	c.Assert(info.fn.argType(1).Kind(), Equals, reflect.Int)
+	c.Assert(info.fn.argType(5).Kind(), Equals, reflect.Int)
+
+	c.Assert(types.get(reflect.TypeOf(MyStruct{})).fn, IsNil)
+}
+
+func (s *CandySuite) TestTypeInfo_Converter(c *C) {
+	type month int
+	convert := func(v interface{}, js string) interface{} {
+		c.Assert(s.ctx.PevalString(js), IsNil)
+		defer s.ctx.Pop()
+
+		return types.get(reflect.TypeOf(v).Elem()).convert(s.ctx, -1).Interface()
+	}
+
+	c.Assert(convert((*int8)(nil), `42`), Equals, int8(42))
+	c.Assert(convert((*uint)(nil), `42`), Equals, uint(42))
+	c.Assert(convert((*month)(nil), `3`), Equals, month(3))
+	c.Assert(convert((*float32)(nil), `1.5`), Equals, float32(1.5))
+	c.Assert(convert((*bool)(nil), `true`), Equals, true)
+	c.Assert(convert((*string)(nil), `'foo'`), Equals, "foo")
+	c.Assert(convert((*interface{})(nil), `1.5`), Equals, 1.5)
+	c.Assert(convert((*interface{})(nil), `'foo'`), Equals, "foo")
+	c.Assert(convert((*MyStruct)(nil), `({int: 42})`), DeepEquals, MyStruct{Int: 42})
+}
+
+func (s *CandySuite) TestTypeInfo_ConverterFn(c *C) {
+	info := types.get(reflect.TypeOf(func(string, ...int) {})).fn
+	c.Assert(info.conv, HasLen, 2)
+	c.Assert(info.argConverter(0), NotNil)
+	c.Assert(info.argConverter(5), NotNil)
+
+	c.Assert(s.ctx.PevalString(`42`), IsNil)
+	c.Assert(info.argConverter(3)(s.ctx, -1).Interface(), Equals, 42)
+}
+
+func (s *CandySuite) TestTypeInfo_ConverterUnmarshaler(c *C) {
+	s.ctx.PushGlobalGoFunction("test", func(v jsonNumber, n int) string {
+		return string(v) + "," + fmt.Sprint(n)
+	})
+
+	c.Assert(s.ctx.PevalString(`test(42, 21)`), IsNil)
+	c.Assert(s.ctx.GetString(-1), Equals, "n42,21")
+}
+
+type jsonNumber string
+
+func (n *jsonNumber) UnmarshalJSON(data []byte) error {
+	*n = jsonNumber("n" + string(data))
+	return nil
+}
+
+func (s *CandySuite) BenchmarkProxyGet(c *C) {
+	s.ctx.PushGlobalGoFunction("benchmark", func() {
+		for i := 0; i < c.N; i++ {
+			p.get(&MyStruct{Int: 42}, "float64", nil)
+		}
+	})
+
+	c.ResetTimer()
+	c.Assert(s.ctx.PevalString(`benchmark()`), IsNil)
+}
+
+func (s *CandySuite) BenchmarkProxyScript(c *C) {
+	s.ctx.PushGlobalProxy("test", &MyStruct{Int: 42, Nested: &MyStruct{Int: 21}})
+	s.ctx.PushGlobalInterface("n", c.N)
+
+	c.ResetTimer()
+	c.Assert(s.ctx.PevalString(`
+		for (var i = 0; i < n; i++) {
+			test.int = test.nested.int + test.multiply(2);
+			test.float64 = test.uInt64;
+		}
+	`), IsNil)
+}
+
+func (s *CandySuite) BenchmarkGoFunctionCall(c *C) {
+	s.ctx.PushGlobalGoFunction("test", func(m *MyStruct, a, b int) *MyStruct {
+		return &MyStruct{Int: m.Int + a*b}
+	})
+
+	s.ctx.PushGlobalInterface("n", c.N)
+
+	c.ResetTimer()
+	c.Assert(s.ctx.PevalString(`
+		var m = test({int: 1}, 2, 3);
+		for (var i = 0; i < n; i++) {
+			m = test(m, i, 2);
+		}
+	`), IsNil)
+}
+
+type embeddedStruct struct {
+	MyStruct
+	Qux string
+}